@@ -21,10 +21,22 @@ func NewModel() Model {
 	vp := viewport.New(80, 20)
 
 	playlistStore, _ := utils.NewPlaylistStore()
+	eqStore, _ := utils.NewEqualizerPresetStore()
+	historyStore, _ := utils.NewHistoryStore()
+	statsStore, _ := utils.NewStatsStore()
+	settings, _ := utils.LoadSettings()
 
 	cwd, _ := os.Getwd()
 	fileExplorer := utils.NewFileExplorer(cwd)
 
+	session, _ := utils.LoadSession()
+	if session != nil && session.LibraryRoot == "" {
+		session = nil
+	}
+	if session != nil {
+		ti.SetValue(session.LibraryRoot)
+	}
+
 	prog := progress.New(progress.WithScaledGradient(string(colorAccent), string(colorSuccess)),
 		progress.WithoutPercentage(),
 	)
@@ -34,7 +46,7 @@ func NewModel() Model {
 	prog.Empty = '▄'
 	prog.EmptyColor = string(colorSubtle)
 
-	return Model{
+	m := Model{
 		viewport:        vp,
 		textInput:       ti,
 		progressBar:     prog,
@@ -57,5 +69,20 @@ func NewModel() Model {
 		cachedTrackPath: "",
 		librarySection:  SectionPlaylists,
 		currentFilter:   TrackFilter{Type: FilterAll, Key: "", Label: "All Tracks"},
+		eqStore:         eqStore,
+		showEqualizer:   false,
+		eqBand:          0,
+		eqPreset:        "Flat",
+		showQueue:       false,
+		queueIndex:      0,
+		playingContext:  "All Tracks",
+		playingFilter:   &TrackFilter{Type: FilterAll, Label: "All Tracks"},
+		pendingSession:  session,
+		historyStore:    historyStore,
+		statsStore:      statsStore,
+		settings:        settings,
+		spectrum:        utils.NewSpectrumAnalyzer(),
+		scanning:        session != nil,
 	}
+	return m.refreshHistoryDays()
 }
@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+type startScanMsg struct {
+	dir string
+}
+
+type scanProgressMsg struct {
+	id       int
+	progress utils.ScanProgress
+}
+
+// startScan cancels any scan still running and starts scanning dir. Progress
+// arrives as scanProgressMsg and the result as scanMsg, both tagged with the
+// scan's id so that messages from a cancelled scan are ignored.
+func (m Model) startScan(dir string) (Model, tea.Cmd) {
+	if m.scanCancel != nil {
+		m.scanCancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := make(chan utils.ScanProgress)
+
+	m.scanID++
+	m.scanCancel = cancel
+	m.scanEvents = progress
+	m.scanProgress = utils.ScanProgress{}
+	m.scanning = true
+	m.scanDir = dir
+	m.errorMsg = ""
+
+	return m, tea.Batch(scanTracks(ctx, m.scanID, dir, m.settings.ScanWorkers, progress), waitForScanProgress(m.scanID, progress))
+}
+
+// openPlayer switches to player mode over tracks, restoring session when there
+// is one and otherwise starting playback from the first track.
+func (m Model) openPlayer(dir string, tracks []utils.Track, session *utils.Session) (Model, tea.Cmd) {
+	m.tracks = tracks
+	m.libraryVersion++
+	m.libraryRoot = dir
+	m.mode = ModePlayer
+	m.player = utils.NewPlayer(m.tracks)
+	m.player.ApplySettings(m.settings)
+	m.playerEvents, _ = m.player.Subscribe()
+	if m.historyStore != nil {
+		m.historyStore.Watch(m.player)
+	}
+	if m.statsStore != nil {
+		m.statsStore.AddTracks(m.tracks, time.Now())
+		m.statsStore.Watch(m.player)
+		m.player.SetShuffleWeight(m.statsStore.ShuffleWeight)
+	}
+	if session != nil {
+		m = m.restoreSession(session)
+	} else {
+		m.player.Play()
+		m.lastTrackIdx = m.player.GetCurrentIndex()
+	}
+	return m, waitForPlayerEvent(m.playerEvents)
+}
+
+func waitForScanProgress(id int, events <-chan utils.ScanProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-events
+		if !ok {
+			return nil
+		}
+		return scanProgressMsg{id: id, progress: progress}
+	}
+}
+
+func (m Model) finishScan() Model {
+	m.scanning = false
+	m.scanCancel = nil
+	m.scanEvents = nil
+	return m
+}
+
+// handleScanProgress records the latest counters and makes the tracks read so
+// far playable: the first batch opens the player and later batches are added
+// to it. A session being restored waits for the whole library instead.
+func (m Model) handleScanProgress(progress utils.ScanProgress) (Model, tea.Cmd) {
+	m.scanProgress = progress
+	if len(progress.Tracks) == 0 || m.pendingSession != nil {
+		return m, nil
+	}
+
+	if m.player == nil {
+		return m.openPlayer(m.scanDir, progress.Tracks, nil)
+	}
+
+	m.tracks = append(m.tracks[:len(m.tracks):len(m.tracks)], progress.Tracks...)
+	m.libraryVersion++
+	if m.statsStore != nil {
+		m.statsStore.AddTracks(progress.Tracks, time.Now())
+	}
+	if m.playingAllTracks() {
+		m.player.AppendTracks(progress.Tracks)
+	} else {
+		m.syncPlayingContext()
+	}
+	return m, nil
+}
+
+func (m Model) renderScanProgress() string {
+	var b strings.Builder
+	progress := m.scanProgress
+
+	b.WriteString(statusStyle.Render("⏳ Scanning "+m.scanDir) + "\n\n")
+
+	rows := [][2]string{
+		{"Directories", fmt.Sprintf("%d", progress.Dirs)},
+		{"Files found", fmt.Sprintf("%d", progress.Found)},
+		{"Files read", fmt.Sprintf("%d / %d", progress.Parsed, progress.Queued)},
+		{"Errors", fmt.Sprintf("%d", progress.Errors)},
+	}
+
+	elapsed := formatTime(progress.Elapsed)
+	if eta, ok := progress.ETA(); ok {
+		elapsed += "  (about " + formatTime(eta) + " left)"
+	}
+	rows = append(rows, [2]string{"Elapsed", elapsed})
+
+	for _, row := range rows {
+		b.WriteString(subtleStyle.Render(fmt.Sprintf("  %-12s ", row[0])) + row[1] + "\n")
+	}
+
+	if progress.Current != "" {
+		b.WriteString("\n" + subtleStyle.Render("  "+m.truncate(progress.Current, m.width-6)) + "\n")
+	}
+
+	b.WriteString("\n" + subtleStyle.Render("ESC: cancel"))
+	return b.String()
+}
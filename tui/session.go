@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+const sessionSaveInterval = 15 * time.Second
+
+type sessionSaveMsg struct{}
+
+func scheduleSessionSave() tea.Cmd {
+	return tea.Tick(sessionSaveInterval, func(time.Time) tea.Msg {
+		return sessionSaveMsg{}
+	})
+}
+
+func writeSession(session *utils.Session) tea.Cmd {
+	return func() tea.Msg {
+		utils.SaveSession(session)
+		return nil
+	}
+}
+
+func (m Model) buildSession() *utils.Session {
+	if m.player == nil || m.libraryRoot == "" {
+		return nil
+	}
+
+	session := &utils.Session{
+		LibraryRoot:  m.libraryRoot,
+		ContextLabel: m.playingContext,
+		Filter: utils.SessionFilter{
+			Type:  int(m.currentFilter.Type),
+			Key:   m.currentFilter.Key,
+			Label: m.currentFilter.Label,
+		},
+		LibrarySection: int(m.librarySection),
+		SelectedIndex:  m.selectedIndex,
+		PlaylistIndex:  m.playlistIndex,
+		AlbumIndex:     m.albumIndex,
+		ArtistIndex:    m.artistIndex,
+		HistoryIndex:   m.historyIndex,
+		StatsIndex:     m.statsIndex,
+	}
+	if filter := m.playingFilter; filter != nil {
+		session.ContextFilter = &utils.SessionFilter{Type: int(filter.Type), Key: filter.Key, Label: filter.Label}
+	}
+	session.SetPlaybackState(m.player.State())
+	// A context taken from the library is rebuilt from its filter, which
+	// saves writing out every path in it.
+	if m.playingFilter != nil && m.playingFilter.Type.fromLibrary() {
+		session.Context = nil
+	}
+	return session
+}
+
+func (m Model) restoreSession(session *utils.Session) Model {
+	m.currentFilter = TrackFilter{
+		Type:  FilterType(session.Filter.Type),
+		Key:   session.Filter.Key,
+		Label: session.Filter.Label,
+	}
+	if m.currentFilter.Type == FilterPlaylist {
+		m.currentPlaylist = m.currentFilter.Key
+	}
+	if len(m.getFilteredTracks()) == 0 {
+		m.setFilterAll()
+	}
+
+	m.librarySection = LibrarySection(session.LibrarySection)
+	m.playlistIndex = session.PlaylistIndex
+	m.albumIndex = session.AlbumIndex
+	m.artistIndex = session.ArtistIndex
+	m.historyIndex = session.HistoryIndex
+	m.statsIndex = session.StatsIndex
+
+	m.selectedIndex = session.SelectedIndex
+	if tracks := m.getFilteredTracks(); m.selectedIndex < 0 || m.selectedIndex >= len(tracks) {
+		m.selectedIndex = 0
+	}
+
+	if session.ContextLabel != "" {
+		m.playingContext = session.ContextLabel
+	}
+	m.playingFilter = nil
+	if filter := session.ContextFilter; filter != nil {
+		m.playingFilter = &TrackFilter{Type: FilterType(filter.Type), Key: filter.Key, Label: filter.Label}
+	}
+
+	var context []utils.Track
+	if m.playingFilter != nil && m.playingFilter.Type.fromLibrary() {
+		context, _ = m.filterTracks(*m.playingFilter)
+	}
+	if err := m.player.RestoreState(session.PlaybackState(m.tracks, context)); err != nil {
+		m.errorMsg = err.Error()
+	}
+	m.lastTrackIdx = m.player.GetCurrentIndex()
+
+	return m
+}
@@ -9,6 +9,7 @@ var (
 	colorSuccess = lipgloss.Color("#6ee7b7")
 	colorDanger  = lipgloss.Color("#FF0000")
 	colorSubtle  = lipgloss.Color("#9ca3b0")
+	colorLoop    = lipgloss.Color("#fbbf24")
 
 	appStyle = lipgloss.NewStyle().
 			Background(colorBg).
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -9,7 +10,12 @@ import (
 )
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, tea.EnterAltScreen, tick())
+	cmds := []tea.Cmd{textinput.Blink, tea.EnterAltScreen, tick(), scheduleSessionSave()}
+	if m.pendingSession != nil {
+		dir := m.pendingSession.LibraryRoot
+		cmds = append(cmds, func() tea.Msg { return startScanMsg{dir: dir} })
+	}
+	return tea.Batch(cmds...)
 }
 
 func tick() tea.Cmd {
@@ -18,10 +24,41 @@ func tick() tea.Cmd {
 	})
 }
 
-func scanTracks(dir string) tea.Cmd {
+func waitForPlayerEvent(events <-chan utils.PlayerEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return playerEventMsg(event)
+	}
+}
+
+// scanTracks loads the saved index for dir when there is one, leaving the
+// rescan to refreshLibrary, and scans dir from scratch otherwise, reporting
+// progress on the way.
+func scanTracks(ctx context.Context, id int, dir string, workers int, progress chan utils.ScanProgress) tea.Cmd {
+	return func() tea.Msg {
+		if index, err := utils.LoadLibraryIndex(dir); err == nil && index != nil {
+			close(progress)
+			return scanMsg{id: id, dir: dir, tracks: index.Tracks(), index: index, cached: true}
+		}
+
+		index, _, err := utils.ScanLibrary(ctx, dir, nil, utils.ScanOptions{Workers: workers, Progress: progress})
+		if err == nil {
+			index.Save()
+		}
+		return scanMsg{id: id, dir: dir, tracks: index.Tracks(), index: index, err: err}
+	}
+}
+
+func refreshLibrary(dir string, previous *utils.LibraryIndex, workers int) tea.Cmd {
 	return func() tea.Msg {
-		tracks, err := utils.ScanDir(dir)
-		return scanMsg{tracks: tracks, err: err}
+		index, changed, err := utils.ScanLibrary(context.Background(), dir, previous, utils.ScanOptions{Workers: workers})
+		if err == nil && changed {
+			err = index.Save()
+		}
+		return libraryRefreshMsg{dir: dir, tracks: index.Tracks(), index: index, changed: changed, err: err}
 	}
 }
 
@@ -30,18 +67,37 @@ func (m Model) handleInputSubmit() Model {
 
 	switch m.inputMode {
 	case InputPlaylistName:
-		if err := m.playlistStore.CreatePlaylist(value); err != nil {
+		if m.playlistStore == nil {
+			m.errorMsg = "playlists are unavailable"
+		} else if err := m.playlistStore.CreatePlaylist(value); err != nil {
 			m.errorMsg = err.Error()
 		} else {
 			m.currentPlaylist = value
 		}
 
 	case InputPlaylistLoad:
-		if _, err := m.playlistStore.GetPlaylist(value); err != nil {
+		if m.playlistStore == nil {
+			m.errorMsg = "playlists are unavailable"
+		} else if _, err := m.playlistStore.GetPlaylist(value); err != nil {
 			m.errorMsg = err.Error()
 		} else {
 			m.currentPlaylist = value
 		}
+
+	case InputEqualizerPreset:
+		if m.eqStore == nil || m.player == nil {
+			m.errorMsg = "equalizer presets are unavailable"
+		} else if err := m.eqStore.SavePreset(value, m.player.GetEqualizerGains()); err != nil {
+			m.errorMsg = err.Error()
+		} else {
+			m.eqPreset = value
+		}
+
+	case InputSeek:
+		m = m.handleSeekInput(value)
+
+	case InputHistoryPlaylist:
+		m = m.createHistoryPlaylist(value)
 	}
 
 	m.inputMode = InputNone
@@ -49,10 +105,52 @@ func (m Model) handleInputSubmit() Model {
 	return m
 }
 
-func (m Model) handleEnter() Model {
-	if m.mode == ModeScan && !m.scanning {
-		m.scanning = true
+func (m Model) handleSeekInput(value string) Model {
+	if m.player == nil {
 		return m
 	}
+
+	position, relative, err := utils.ParseSeekInput(value)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return m
+	}
+
+	if relative {
+		err = m.player.SeekBy(position)
+	} else {
+		err = m.player.SeekTo(position)
+	}
+	if err != nil {
+		m.errorMsg = err.Error()
+	}
 	return m
 }
+
+func (m Model) handlePlayerEvent(event utils.PlayerEvent) Model {
+	switch event.Type {
+	case utils.EventTrackStarted:
+		m.lastTrackIdx = event.Index
+		m.errorMsg = ""
+
+	case utils.EventPlaybackError:
+		m.errorMsg = "Playback error: " + event.Err.Error()
+
+	case utils.EventQueueChanged:
+		if queueLen := len(m.player.GetQueue()); m.queueIndex >= queueLen {
+			m.queueIndex = queueLen - 1
+			if m.queueIndex < 0 {
+				m.queueIndex = 0
+			}
+		}
+	}
+
+	return m
+}
+
+func (m Model) handleEnter() (Model, tea.Cmd) {
+	if m.mode == ModeScan && !m.scanning {
+		return m.startScan(m.textInput.Value())
+	}
+	return m, nil
+}
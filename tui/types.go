@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
@@ -14,14 +15,28 @@ type InputMode int
 
 type tickMsg time.Time
 
+type playerEventMsg utils.PlayerEvent
+
 type LibrarySection int
 type FilterType int
 
 type scanMsg struct {
+	id     int
+	dir    string
 	tracks []utils.Track
+	index  *utils.LibraryIndex
+	cached bool
 	err    error
 }
 
+type libraryRefreshMsg struct {
+	dir     string
+	tracks  []utils.Track
+	index   *utils.LibraryIndex
+	changed bool
+	err     error
+}
+
 type TrackFilter struct {
 	Type  FilterType
 	Key   string
@@ -36,6 +51,17 @@ type AlbumGroup struct {
 	Tracks []utils.Track
 }
 
+type HistoryDay struct {
+	Key   string
+	Label string
+	Plays []utils.HistoryEntry
+}
+
+type StatsList struct {
+	Key   string
+	Label string
+}
+
 type ArtistGroup struct {
 	Key    string
 	Name   string
@@ -47,12 +73,18 @@ const (
 	FilterPlaylist
 	FilterAlbum
 	FilterArtist
+	FilterHistory
+	FilterStats
 )
 
 const (
 	SectionPlaylists LibrarySection = iota
 	SectionAlbums
 	SectionArtists
+	SectionHistory
+	SectionStats
+
+	librarySectionCount = 5
 )
 
 const (
@@ -66,6 +98,9 @@ const (
 	InputNone InputMode = iota
 	InputPlaylistName
 	InputPlaylistLoad
+	InputEqualizerPreset
+	InputSeek
+	InputHistoryPlaylist
 )
 
 type Model struct {
@@ -94,4 +129,41 @@ type Model struct {
 	cachedTrackPath string
 	librarySection  LibrarySection
 	currentFilter   TrackFilter
+	eqStore         *utils.EqualizerPresetStore
+	showEqualizer   bool
+	eqBand          int
+	eqPreset        string
+	showQueue       bool
+	queueIndex      int
+	playingContext  string
+	playingFilter   *TrackFilter
+	playerEvents    <-chan utils.PlayerEvent
+	libraryRoot     string
+	pendingSession  *utils.Session
+	historyStore    *utils.HistoryStore
+	historyIndex    int
+	historyDays     []HistoryDay
+	historyVersion  int
+	historyDate     string
+	statsStore      *utils.StatsStore
+	statsIndex      int
+	statsCache      statsCache
+	totals          totalsCache
+	libraryVersion  int
+	settings        utils.Settings
+	spectrum        *utils.SpectrumAnalyzer
+	showSpectrum    bool
+	showWaveform    bool
+	waveform        *utils.Waveform
+	waveformPath    string
+	waveformLoading string
+	scanID          int
+	scanDir         string
+	scanCancel      context.CancelFunc
+	scanEvents      <-chan utils.ScanProgress
+	scanProgress    utils.ScanProgress
+	libraryIndex    *utils.LibraryIndex
+	watcher         *utils.LibraryWatcher
+	refreshing      bool
+	refreshPending  bool
 }
@@ -1,6 +1,11 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/ryansantos40/go-music-player/utils"
@@ -13,18 +18,38 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" || (msg.String() == "q" && m.inputMode == InputNone && m.mode != ModeScan) {
+			if session := m.buildSession(); session != nil {
+				utils.SaveSession(session)
+			}
 			if m.player != nil {
-				m.player.Stop()
+				m.player.Close()
+			}
+			if m.statsStore != nil {
+				m.statsStore.Flush()
+			}
+			if m.scanCancel != nil {
+				m.scanCancel()
+			}
+			if m.watcher != nil {
+				m.watcher.Close()
 			}
 			return m, tea.Quit
 		}
 
 		if msg.String() == "esc" {
+			if m.scanning && m.scanCancel != nil && m.inputMode == InputNone && !m.showEqualizer {
+				m.scanCancel()
+				return m, nil
+			}
 			if m.mode == ModeExplorer {
 				m.mode = ModeScan
 				m.explorerIndex = 0
 				return m, nil
 			}
+			if m.showEqualizer && m.inputMode == InputNone {
+				m.showEqualizer = false
+				return m, nil
+			}
 			m.inputMode = InputNone
 			m.textInput.Reset()
 			m.errorMsg = ""
@@ -39,8 +64,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				if m.mode == ModeScan {
-					m = m.handleEnter()
-					return m, scanTracks(m.textInput.Value())
+					return m.handleEnter()
 				}
 			}
 
@@ -69,8 +93,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.explorerIndex = 0
 			case "enter":
 				selectedPath := m.fileExplorer.GetCurrentPath()
-				m.scanning = true
-				return m, scanTracks(selectedPath)
+				m.textInput.SetValue(selectedPath)
+				m.mode = ModeScan
+				return m.startScan(selectedPath)
 			case "backspace", "h":
 				m.fileExplorer.GoToParent()
 				m.explorerIndex = 0
@@ -79,19 +104,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if m.mode == ModePlayer {
+			if m.showEqualizer && m.handleEqualizerKey(msg.String()) {
+				return m, nil
+			}
+
 			switch msg.String() {
 			case "tab":
 				m.focusedColumn = (m.focusedColumn + 1) % 2
 
 			case "[":
 				if m.focusedColumn == 0 {
-					m.librarySection = (m.librarySection + 2) % 3
+					m.librarySection = (m.librarySection + librarySectionCount - 1) % librarySectionCount
 					m.syncLibrarySelection()
 				}
 
 			case "]":
 				if m.focusedColumn == 0 {
-					m.librarySection = (m.librarySection + 1) % 3
+					m.librarySection = (m.librarySection + 1) % librarySectionCount
 					m.syncLibrarySelection()
 				}
 
@@ -99,6 +128,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.focusedColumn == 0 {
 					m.navigateLibrary(-1)
 
+				} else if m.showQueue {
+					m.navigateQueue(-1)
 				} else {
 					m.navigateTracks(-1)
 				}
@@ -107,13 +138,38 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.focusedColumn == 0 {
 					m.navigateLibrary(1)
 
+				} else if m.showQueue {
+					m.navigateQueue(1)
 				} else {
 					m.navigateTracks(1)
 				}
 
 			case "enter":
 				if m.focusedColumn == 1 && m.player != nil {
-					m.handleTrackSelection()
+					if m.showQueue {
+						m.handleQueueSelection()
+					} else {
+						m.handleTrackSelection()
+					}
+				}
+			case "Q":
+				m.showQueue = !m.showQueue
+				m.queueIndex = 0
+			case "N":
+				if m.focusedColumn == 1 && !m.showQueue && m.player != nil {
+					m.enqueueSelection(true)
+				}
+			case "B":
+				if m.focusedColumn == 1 && !m.showQueue && m.player != nil {
+					m.enqueueSelection(false)
+				}
+			case "K":
+				if m.focusedColumn == 1 && m.showQueue && m.player != nil {
+					m.moveQueueSelection(-1)
+				}
+			case "J":
+				if m.focusedColumn == 1 && m.showQueue && m.player != nil {
+					m.moveQueueSelection(1)
 				}
 			case " ":
 				if m.player != nil {
@@ -133,22 +189,96 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			case "s":
 				if m.player != nil {
-					m.player.ToggleShuffle()
+					m.player.CycleShuffle()
 				}
 			case "r":
 				if m.player != nil {
 					m.player.ToggleRepeat()
 				}
+			case "f":
+				if m.player != nil {
+					m.player.CycleCrossfade()
+				}
+			case "F":
+				if m.player != nil {
+					m.player.ToggleSmartCrossfade()
+				}
+			case "g":
+				if m.player != nil {
+					m.player.CycleReplayGainMode()
+				}
+			case "G":
+				if m.player != nil {
+					m.player.CyclePreamp()
+				}
+			case "e":
+				if m.player != nil {
+					m.showEqualizer = true
+				}
+			case "v":
+				m.showSpectrum = !m.showSpectrum
+			case "w":
+				m.showWaveform = !m.showWaveform
+				m, cmd = m.requestWaveform()
+				return m, cmd
 			case "right", "l":
-				if m.player != nil && m.player.IsPlaying() {
+				if m.player != nil {
 					m.player.SeekForward()
 					return m, tick()
 				}
 			case "left", "h":
-				if m.player != nil && m.player.IsPlaying() {
+				if m.player != nil {
 					m.player.SeekBackward()
 					return m, tick()
 				}
+			case "shift+right", "L":
+				if m.player != nil {
+					m.player.SeekForwardLarge()
+					return m, tick()
+				}
+			case "shift+left", "H":
+				if m.player != nil {
+					m.player.SeekBackwardLarge()
+					return m, tick()
+				}
+			case "<", ",":
+				if m.player != nil {
+					m.player.SetSpeed(m.player.GetSpeed() - 0.1)
+				}
+			case ">", ".":
+				if m.player != nil {
+					m.player.SetSpeed(m.player.GetSpeed() + 0.1)
+				}
+			case "P":
+				if m.player != nil {
+					m.player.TogglePreservePitch()
+				}
+			case "A":
+				if m.focusedColumn == 1 && !m.showQueue {
+					m.jumpToAlbum()
+				}
+			case "M":
+				if m.historyStore != nil {
+					m.inputMode = InputHistoryPlaylist
+					m.textInput.Placeholder = "2006-01-02..2006-01-31, today, 7d..."
+					m.textInput.Focus()
+				}
+			case "z":
+				if m.player != nil {
+					m.player.CycleSleepTimer()
+				}
+			case "b":
+				if m.player != nil {
+					if err := m.player.CycleLoop(); err != nil {
+						m.errorMsg = err.Error()
+					}
+				}
+			case "t":
+				if m.player != nil {
+					m.inputMode = InputSeek
+					m.textInput.Placeholder = "1:23:45, +30s, -2m..."
+					m.textInput.Focus()
+				}
 			case "+", "=":
 				if m.player != nil {
 					m.player.SetVolume(m.player.GetVolume() + 0.1)
@@ -164,19 +294,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "a":
 				if m.currentPlaylist != "" && m.player != nil {
 					track := m.player.GetCurrentTrack()
-					if err := m.playlistStore.AddTrack(m.currentPlaylist, track); err != nil {
+					if m.playlistStore == nil {
+						m.errorMsg = "playlists are unavailable"
+					} else if err := m.playlistStore.AddTrack(m.currentPlaylist, track); err != nil {
 						m.errorMsg = err.Error()
 					}
 				}
 			case "x":
-				if m.currentPlaylist != "" && m.focusedColumn == 1 {
-					if err := m.playlistStore.RemoveTrack(m.currentPlaylist, m.selectedIndex); err != nil {
+				if m.showQueue && m.focusedColumn == 1 && m.player != nil {
+					if err := m.player.RemoveFromQueue(m.queueIndex); err != nil {
+						m.errorMsg = err.Error()
+					} else if m.queueIndex > 0 && m.queueIndex >= len(m.player.GetQueue()) {
+						m.queueIndex--
+					}
+				} else if m.currentPlaylist != "" && m.focusedColumn == 1 {
+					if m.playlistStore == nil {
+						m.errorMsg = "playlists are unavailable"
+					} else if err := m.playlistStore.RemoveTrack(m.currentPlaylist, m.selectedIndex); err != nil {
 						m.errorMsg = err.Error()
 					}
 				}
 			case "d":
 				if m.currentPlaylist != "" && m.focusedColumn == 0 {
-					if err := m.playlistStore.DeletePlaylist(m.currentPlaylist); err != nil {
+					if m.playlistStore == nil {
+						m.errorMsg = "playlists are unavailable"
+					} else if err := m.playlistStore.DeletePlaylist(m.currentPlaylist); err != nil {
 						m.errorMsg = err.Error()
 					} else {
 						m.currentPlaylist = ""
@@ -186,18 +328,103 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case startScanMsg:
+		m, cmd = m.startScan(msg.dir)
+		cmds = append(cmds, cmd)
+
+	case scanProgressMsg:
+		if msg.id == m.scanID && m.scanning {
+			m, cmd = m.handleScanProgress(msg.progress)
+			cmds = append(cmds, cmd, waitForScanProgress(msg.id, m.scanEvents))
+		}
+
 	case scanMsg:
-		m.scanning = false
-		if msg.err != nil {
+		if msg.id != m.scanID {
+			break
+		}
+		m = m.finishScan()
+		session := m.pendingSession
+		m.pendingSession = nil
+
+		switch {
+		case errors.Is(msg.err, context.Canceled) && m.player != nil:
+			m.errorMsg = fmt.Sprintf("Scan stopped, %s loaded", pluralize(len(m.tracks), "track"))
+		case errors.Is(msg.err, context.Canceled):
+			m.mode = ModeScan
+			m.errorMsg = "Scan cancelled"
+		case msg.err != nil && m.player != nil:
+			m.errorMsg = "Scan failed: " + msg.err.Error()
+		case msg.err != nil:
 			m.errorMsg = "Error: " + msg.err.Error()
 			m.mode = ModeExplorer
+		case m.player != nil:
+			m.libraryIndex = msg.index
+			m.refreshLibraryTracks(msg.tracks)
+			m, cmd = m.watchLibrary(msg.dir)
+			cmds = append(cmds, cmd)
+		default:
+			m, cmd = m.openPlayer(msg.dir, msg.tracks, session)
+			cmds = append(cmds, cmd)
+			m.libraryIndex = msg.index
+			if msg.cached {
+				m, cmd = m.startLibraryRefresh()
+				cmds = append(cmds, cmd)
+			}
+			m, cmd = m.watchLibrary(msg.dir)
+			cmds = append(cmds, cmd)
+		}
+
+	case libraryRefreshMsg:
+		if msg.dir != m.libraryRoot {
+			break
+		}
+		m.refreshing = false
+		if msg.err != nil {
+			m.errorMsg = "Library refresh failed: " + msg.err.Error()
 		} else {
-			m.tracks = msg.tracks
-			m.mode = ModePlayer
-			m.player = utils.NewPlayer(m.tracks)
-			m.player.Play()
-			m.lastTrackIdx = m.player.GetCurrentIndex()
+			m.libraryIndex = msg.index
+			if msg.changed {
+				m.refreshLibraryTracks(msg.tracks)
+			}
+		}
+		if m.refreshPending {
+			m, cmd = m.startLibraryRefresh()
+			cmds = append(cmds, cmd)
+		}
+
+	case libraryChangedMsg:
+		if m.watcher != nil && msg.dir == m.watcher.Root() {
+			cmds = append(cmds, waitForLibraryChange(m.watcher))
+			if msg.dir == m.libraryRoot {
+				m, cmd = m.startLibraryRefresh()
+				cmds = append(cmds, cmd)
+			}
+		}
+
+	case libraryWatchErrorMsg:
+		if m.watcher != nil && msg.dir == m.watcher.Root() {
+			cmds = append(cmds, waitForLibraryChange(m.watcher))
+			m.errorMsg = "Not watching all of the library: " + msg.err.Error()
+		}
+
+	case sessionSaveMsg:
+		if session := m.buildSession(); session != nil {
+			cmds = append(cmds, writeSession(session))
 		}
+		cmds = append(cmds, scheduleSessionSave())
+
+	case playerEventMsg:
+		m = m.handlePlayerEvent(utils.PlayerEvent(msg))
+		m = m.refreshHistoryDays()
+		m = m.refreshStats()
+		cmds = append(cmds, waitForPlayerEvent(m.playerEvents))
+		if msg.Type == utils.EventTrackStarted {
+			m, cmd = m.requestWaveform()
+			cmds = append(cmds, cmd)
+		}
+
+	case waveformMsg:
+		m = m.handleWaveform(msg)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -207,12 +434,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.progressBar.Width = msg.Width - 2
 
 	case tickMsg:
-		if m.mode == ModePlayer && m.player != nil && m.player.IsPlaying() {
-			currentIdx := m.player.GetCurrentIndex()
-			if currentIdx != m.lastTrackIdx {
-				m.lastTrackIdx = currentIdx
-			}
+		if m.mode == ModePlayer {
+			m.spectrum.Update(m.player, spectrumBands(m.width), time.Time(msg))
 		}
+		// The history and stats stores record plays from their own
+		// subscriptions, so they can lag the player event that caused them.
+		m = m.refreshHistoryDays()
+		m = m.refreshStats()
+		m = m.refreshTotals()
 		cmd = tick()
 		cmds = append(cmds, cmd)
 
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -31,7 +32,11 @@ func (m Model) renderPlayerMode() string {
 	b.WriteString("\n")
 	b.WriteString(m.renderProgressBar())
 	b.WriteString("\n")
-	b.WriteString(m.renderColumns())
+	if m.showEqualizer {
+		b.WriteString(m.renderEqualizer())
+	} else {
+		b.WriteString(m.renderColumns())
+	}
 	b.WriteString("\n")
 	b.WriteString(m.renderCommands())
 
@@ -45,10 +50,14 @@ func (m Model) renderScanMode() string {
 	b.WriteString(inputStyle.Render(m.textInput.View()) + "\n\n")
 
 	if m.scanning {
-		b.WriteString(statusStyle.Render("⏳ Scanning directory...") + "\n")
-	} else {
-		b.WriteString(subtleStyle.Render("Press Enter to start scanning or 'tab' to use file explorer"))
+		b.WriteString(m.renderScanProgress())
+		return b.String()
+	}
+
+	if m.errorMsg != "" {
+		b.WriteString(errorStyle.Render("✗ "+m.errorMsg) + "\n\n")
 	}
+	b.WriteString(subtleStyle.Render("Press Enter to start scanning or 'tab' to use file explorer"))
 
 	return b.String()
 }
@@ -102,8 +111,15 @@ func (m Model) renderInput() string {
 	var b strings.Builder
 
 	prompt := "Create Playlist"
-	if m.inputMode == InputPlaylistLoad {
+	switch m.inputMode {
+	case InputPlaylistLoad:
 		prompt = "Load Playlist"
+	case InputEqualizerPreset:
+		prompt = "Save Equalizer Preset"
+	case InputSeek:
+		prompt = "Seek To"
+	case InputHistoryPlaylist:
+		prompt = "Playlist From History"
 	}
 
 	b.WriteString(headerStyle.Render(prompt) + "\n\n")
@@ -114,7 +130,68 @@ func (m Model) renderInput() string {
 }
 
 func (m Model) renderCommands() string {
-	commands := "COMMANDS: [C]reate, [D]elete, [ENTER] Select   [A]dd Song, [X]Remove, [SPACE] Play/Pause, [N]ext, [P]rev, [TAB] Switch Column"
+	if m.showEqualizer {
+		return subtleStyle.Width(m.width).Render("EQUALIZER: [←/→] Band, [↑/↓] Gain, [[/]] Preset, [0] Flat, [S]ave Preset, [E/ESC] Close")
+	}
+
+	if m.errorMsg != "" {
+		return errorStyle.Width(m.width).Render("✗ " + m.errorMsg)
+	}
+
+	if m.scanning {
+		progress := m.scanProgress
+		status := fmt.Sprintf("⏳ Scanning: %d of %d files read, %d found · [ESC] Stop", progress.Parsed, progress.Queued, progress.Found)
+		return statusStyle.Width(m.width).Render(status)
+	}
+
+	small, large := 5*time.Second, time.Minute
+	if m.player != nil {
+		small, large = m.player.GetSeekSteps()
+	}
+
+	// Keys are case sensitive, so each is listed exactly as it is typed.
+	bindings := [][2]string{
+		{"tab", "Column"},
+		{"[ ]", "Section"},
+		{"j k", "Move"},
+		{"enter", "Play"},
+		{"space", "Pause"},
+		{"n p", "Next/Prev"},
+		{"h l", "Seek ±" + formatSeekStep(small)},
+		{"H L", "Seek ±" + formatSeekStep(large)},
+		{"t", "Seek To"},
+		{"b", "A-B Loop"},
+		{"< >", "Speed"},
+		{"P", "Keep Pitch"},
+		{"+ -", "Volume"},
+		{"s", "Shuffle"},
+		{"r", "Repeat"},
+		{"f", "Crossfade"},
+		{"F", "Smart Crossfade"},
+		{"g", "ReplayGain"},
+		{"G", "Preamp"},
+		{"e", "Equalizer"},
+		{"v", "Visualizer"},
+		{"w", "Waveform"},
+		{"z", "Sleep"},
+		{"Q", "Queue"},
+		{"N", "Play Next"},
+		{"B", "Add to Queue"},
+		{"K J", "Move in Queue"},
+		{"x", "Remove"},
+		{"c", "New Playlist"},
+		{"a", "Add to Playlist"},
+		{"d", "Delete Playlist"},
+		{"A", "Go to Album"},
+		{"M", "History Playlist"},
+		{"q", "Quit"},
+	}
+
+	parts := make([]string, len(bindings))
+	for i, binding := range bindings {
+		parts[i] = "[" + binding[0] + "] " + binding[1]
+	}
+	commands := strings.Join(parts, "  ")
 
 	cmdStyle := lipgloss.NewStyle().
 		Foreground(colorSubtle).
@@ -123,3 +200,16 @@ func (m Model) renderCommands() string {
 
 	return cmdStyle.Render(commands)
 }
+
+// commandsHeight is the number of lines the wrapped command list takes, which
+// the columns give up to make room for it.
+func (m Model) commandsHeight() int {
+	return lipgloss.Height(m.renderCommands())
+}
+
+func formatSeekStep(d time.Duration) string {
+	if d >= time.Minute && d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
@@ -17,7 +17,7 @@ func (m Model) renderColumns() string {
 	col2ContentWidth := col2Width - 2
 	col3ContentWidth := col3Width - 2
 
-	colHeight := m.height - 14
+	colHeight := m.height - 11 - m.commandsHeight()
 
 	col1Lines := m.getColumnLines(m.renderLibraryColumn(), colHeight)
 	col2Lines := m.getColumnLines(m.renderTracksColumn(), colHeight)
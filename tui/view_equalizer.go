@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+func (m Model) renderEqualizer() string {
+	var b strings.Builder
+
+	title := fmt.Sprintf("--- [ EQUALIZER: %s ] ---", m.eqPreset)
+	if m.player != nil {
+		if preamp := m.player.GetEqualizerPreamp(); preamp < 0 {
+			title = fmt.Sprintf("--- [ EQUALIZER: %s · preamp %.0f dB ] ---", m.eqPreset, preamp)
+		}
+	}
+	b.WriteString(sectionTitleStyle.Width(m.width).Render(title))
+	b.WriteString("\n\n")
+
+	if m.player == nil {
+		b.WriteString(subtleStyle.Render("No player"))
+		return strings.Join(m.getColumnLines(b.String(), m.height-9-m.commandsHeight()), "\n")
+	}
+
+	gains := m.player.GetEqualizerGains()
+	steps := int(utils.MaxEqualizerGain) / 2
+
+	var bars strings.Builder
+	for row := steps; row >= -steps; row-- {
+		level := float64(row * 2)
+		bars.WriteString(subtleStyle.Render(fmt.Sprintf("%+4d dB ", row*2)))
+
+		for band, gain := range gains {
+			cell := "     "
+			switch {
+			case row == 0 && gain == 0:
+				cell = " ─── "
+			case row == 0:
+				cell = " ███ "
+			case row > 0 && gain >= level:
+				cell = " ███ "
+			case row < 0 && gain <= level:
+				cell = " ███ "
+			}
+
+			style := statusStyle
+			if band == m.eqBand {
+				style = selectedStyle
+			}
+			bars.WriteString(" " + style.Render(cell) + " ")
+		}
+		bars.WriteString("\n")
+	}
+
+	bars.WriteString("        ")
+	for band, freq := range utils.EqualizerFrequencies {
+		style := subtleStyle
+		if band == m.eqBand {
+			style = selectedStyle
+		}
+		bars.WriteString(style.Width(7).Align(lipgloss.Center).Render(formatFrequency(freq)))
+	}
+	bars.WriteString("\n        ")
+	for _, gain := range gains {
+		bars.WriteString(subtleStyle.Width(7).Align(lipgloss.Center).Render(fmt.Sprintf("%+.0f", gain)))
+	}
+
+	container := lipgloss.NewStyle().
+		Width(m.width).
+		Align(lipgloss.Center).
+		Background(colorBg)
+
+	b.WriteString(container.Render(bars.String()))
+
+	return strings.Join(m.getColumnLines(b.String(), m.height-9-m.commandsHeight()), "\n")
+}
+
+func formatFrequency(freq float64) string {
+	if freq >= 1000 {
+		return fmt.Sprintf("%.0fk", freq/1000)
+	}
+	return fmt.Sprintf("%.0f", freq)
+}
+
+func (m *Model) handleEqualizerKey(key string) bool {
+	if m.player == nil {
+		return false
+	}
+
+	switch key {
+	case "e":
+		m.showEqualizer = false
+	case "left", "h":
+		m.eqBand = (m.eqBand - 1 + utils.EqualizerBandCount) % utils.EqualizerBandCount
+	case "right", "l":
+		m.eqBand = (m.eqBand + 1) % utils.EqualizerBandCount
+	case "up", "k":
+		m.adjustEqualizerBand(1)
+	case "down", "j":
+		m.adjustEqualizerBand(-1)
+	case "[":
+		m.cycleEqualizerPreset(-1)
+	case "]":
+		m.cycleEqualizerPreset(1)
+	case "0":
+		m.applyEqualizerPreset(utils.BuiltinEqualizerPresets()[0])
+	case "S":
+		m.inputMode = InputEqualizerPreset
+		m.textInput.Placeholder = "Enter preset name..."
+		m.textInput.Focus()
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (m *Model) adjustEqualizerBand(delta float64) {
+	gains := m.player.GetEqualizerGains()
+	if err := m.player.SetEqualizerGain(m.eqBand, gains[m.eqBand]+delta); err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	m.eqPreset = "Custom"
+}
+
+func (m *Model) cycleEqualizerPreset(delta int) {
+	presets := m.equalizerPresets()
+
+	current := -1
+	for i, preset := range presets {
+		if preset.Name == m.eqPreset {
+			current = i
+			break
+		}
+	}
+
+	next := 0
+	if current >= 0 {
+		next = (current + delta + len(presets)) % len(presets)
+	}
+	m.applyEqualizerPreset(presets[next])
+}
+
+func (m *Model) applyEqualizerPreset(preset utils.EqualizerPreset) {
+	m.player.SetEqualizerGains(preset.Gains)
+	m.eqPreset = preset.Name
+}
+
+func (m Model) equalizerPresets() []utils.EqualizerPreset {
+	if m.eqStore == nil {
+		return utils.BuiltinEqualizerPresets()
+	}
+	return m.eqStore.ListPresets()
+}
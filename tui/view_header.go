@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/ryansantos40/go-music-player/utils"
 )
 
 func (m Model) renderHeader() string {
@@ -22,13 +23,49 @@ func (m Model) renderHeader() string {
 	}
 
 	volumeStr := fmt.Sprintf("Vol: %d%%", int(m.player.GetVolume()*100))
+
+	crossfadeStr := "XF: off"
+	if crossfade := m.player.GetCrossfade(); crossfade > 0 {
+		crossfadeStr = fmt.Sprintf("XF: %ds", int(crossfade.Seconds()))
+		if m.player.GetSmartCrossfade() {
+			crossfadeStr += " smart"
+		}
+	}
+	gainStr := fmt.Sprintf("RG: %s", m.player.GetReplayGainMode())
+	if preamp := m.player.GetPreamp(); preamp != 0 {
+		gainStr += fmt.Sprintf(" %+g dB", preamp)
+	}
+
+	if speed := m.player.GetSpeed(); speed != 1 {
+		status += fmt.Sprintf(" %gx", speed)
+		if !m.player.GetPreservePitch() {
+			status += " ♪"
+		}
+	}
+
+	if mode := m.player.GetShuffleMode(); mode != utils.ShuffleOff {
+		status += " ⤮ " + mode.String()
+	}
+
+	if remaining, active := m.player.GetSleepTimer(); active {
+		status += " ⏾ " + formatTime(remaining)
+	} else if stopAfter := m.player.GetStopAfter(); stopAfter != utils.StopAfterNone {
+		status += " ⏾ " + stopAfter.String()
+	}
+
+	if loop := m.player.GetLoop(); loop.Active {
+		status += fmt.Sprintf(" ⟲ A-B %s-%s", formatTime(loop.Start), formatTime(loop.End))
+	} else if loop.HasStart {
+		status += fmt.Sprintf(" ⟲ A %s-", formatTime(loop.Start))
+	}
+
 	timeStr := fmt.Sprintf("Time: %s / %s",
 		formatTime(m.player.GetCurrentTime()),
 		formatTime(m.player.GetTotalTime()))
 
 	left := titleStyle.Render(songInfo)
 	center := titleStyle.Render(" ")
-	right := titleStyle.Render(fmt.Sprintf("%s | %s | %s |", status, volumeStr, timeStr))
+	right := titleStyle.Render(fmt.Sprintf("%s | %s | %s | %s | %s |", status, volumeStr, gainStr, crossfadeStr, timeStr))
 
 	leftWidth := lipgloss.Width(left)
 	centerWidth := lipgloss.Width(center)
@@ -59,6 +96,12 @@ func (m Model) renderProgressBar() string {
 	}
 
 	bar := m.progressBar.ViewAs(progressPercent)
+	if loop := m.player.GetLoop(); loop.HasStart && totalTime > 0 {
+		bar = m.renderLoopProgressBar(progressPercent, loop, totalTime)
+	} else if m.showWaveform && m.waveform != nil && m.waveformPath == m.player.GetCurrentTrack().Path {
+		bar = m.renderWaveformBar(progressPercent)
+	}
+
 	containerStyle := lipgloss.NewStyle().
 		Width(m.width).
 		Align(lipgloss.Center).
@@ -67,6 +110,49 @@ func (m Model) renderProgressBar() string {
 	return containerStyle.Render(bar)
 }
 
+func (m Model) renderLoopProgressBar(percent float64, loop utils.ABLoop, total time.Duration) string {
+	width := m.progressBar.Width
+	if width <= 0 {
+		return ""
+	}
+
+	cell := func(d time.Duration) int {
+		i := int(float64(d) / float64(total) * float64(width))
+		if i >= width {
+			i = width - 1
+		}
+		return i
+	}
+
+	filled := int(percent * float64(width))
+	start, end := cell(loop.Start), width
+	if loop.Active {
+		end = cell(loop.End)
+	}
+
+	fullStyle := lipgloss.NewStyle().Foreground(colorAccent)
+	emptyStyle := lipgloss.NewStyle().Foreground(colorSubtle)
+	loopStyle := lipgloss.NewStyle().Foreground(colorLoop)
+	markerStyle := loopStyle.Bold(true)
+
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		switch {
+		case i == start:
+			b.WriteString(markerStyle.Render("A"))
+		case i == end && loop.Active:
+			b.WriteString(markerStyle.Render("B"))
+		case i < filled:
+			b.WriteString(fullStyle.Render(string(m.progressBar.Full)))
+		case i > start && i < end && loop.Active:
+			b.WriteString(loopStyle.Render(string(m.progressBar.Empty)))
+		default:
+			b.WriteString(emptyStyle.Render(string(m.progressBar.Empty)))
+		}
+	}
+	return b.String()
+}
+
 func formatTime(d time.Duration) string {
 	d = d.Round(time.Second)
 	h := d / time.Hour
@@ -0,0 +1,207 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+func (m Model) buildHistoryDays() []HistoryDay {
+	if m.historyStore == nil {
+		return nil
+	}
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+
+	var days []HistoryDay
+	for _, play := range m.historyStore.Plays() {
+		key := play.Time.Local().Format("2006-01-02")
+		if len(days) == 0 || days[len(days)-1].Key != key {
+			label := key
+			switch key {
+			case today:
+				label = "Today"
+			case yesterday:
+				label = "Yesterday"
+			}
+			days = append(days, HistoryDay{Key: key, Label: label})
+		}
+		days[len(days)-1].Plays = append(days[len(days)-1].Plays, play)
+	}
+	return days
+}
+
+// refreshHistoryDays regroups the history when a play has been recorded or the
+// date has changed, which moves the Today and Yesterday labels.
+func (m Model) refreshHistoryDays() Model {
+	if m.historyStore == nil {
+		return m
+	}
+
+	version := m.historyStore.Version()
+	today := time.Now().Format("2006-01-02")
+	if version == m.historyVersion && today == m.historyDate {
+		return m
+	}
+
+	m.historyDays = m.buildHistoryDays()
+	m.historyVersion = version
+	m.historyDate = today
+	return m
+}
+
+func (m Model) renderHistoryList() string {
+	var b strings.Builder
+	days := m.historyDays
+
+	if len(days) == 0 {
+		b.WriteString(subtleStyle.Render("Nothing played yet"))
+		return b.String()
+	}
+
+	maxVisible := (m.height - 16) / 2
+	start, end := clampWindow(m.historyIndex, len(days), maxVisible)
+
+	for i := start; i < end; i++ {
+		day := days[i]
+		line := fmt.Sprintf("%s (%d plays)", day.Label, len(day.Plays))
+
+		if i == m.historyIndex && m.librarySection == SectionHistory && m.focusedColumn == 0 {
+			b.WriteString(selectedStyle.Render("> " + line))
+		} else if m.currentFilter.Type == FilterHistory && m.currentFilter.Key == day.Key {
+			b.WriteString(statusStyle.Render("* " + line))
+		} else {
+			b.WriteString(subtleStyle.Render("  " + line))
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func (m *Model) applyHistorySelection() {
+	days := m.historyDays
+	if len(days) == 0 {
+		m.setFilterAll()
+		return
+	}
+
+	if m.historyIndex >= len(days) {
+		m.historyIndex = len(days) - 1
+	}
+	if m.historyIndex < 0 {
+		m.historyIndex = 0
+	}
+
+	day := days[m.historyIndex]
+	m.currentPlaylist = ""
+	m.currentFilter = TrackFilter{
+		Type:  FilterHistory,
+		Key:   day.Key,
+		Label: day.Label,
+	}
+	m.selectedIndex = 0
+}
+
+func (m Model) historyPlays(key string) []utils.HistoryEntry {
+	for _, day := range m.historyDays {
+		if day.Key == key {
+			return day.Plays
+		}
+	}
+	return nil
+}
+
+func historyTracks(plays []utils.HistoryEntry) []utils.Track {
+	tracks := make([]utils.Track, len(plays))
+	for i, play := range plays {
+		tracks[i] = play.Track
+	}
+	return tracks
+}
+
+func formatHistoryPlay(play utils.HistoryEntry) string {
+	line := fmt.Sprintf("%s %s - %s", play.Time.Local().Format("15:04"), play.Track.Title, play.Track.Artist)
+	switch play.Event {
+	case utils.HistorySkipped:
+		line += fmt.Sprintf(" (skipped at %s)", formatTime(play.Listened))
+	case utils.HistoryStopped:
+		line += fmt.Sprintf(" (stopped at %s)", formatTime(play.Listened))
+	}
+	return line
+}
+
+func (m *Model) jumpToAlbum() {
+	tracks := m.getFilteredTracks()
+	if len(tracks) == 0 || m.selectedIndex >= len(tracks) {
+		return
+	}
+	path := tracks[m.selectedIndex].Path
+
+	for i, album := range m.buildAlbumGroups() {
+		for j, track := range album.Tracks {
+			if track.Path != path {
+				continue
+			}
+
+			m.librarySection = SectionAlbums
+			m.albumIndex = i
+			m.applyAlbumSelection()
+			m.selectedIndex = j
+			return
+		}
+	}
+
+	m.errorMsg = "track is not in the library"
+}
+
+func (m Model) createHistoryPlaylist(value string) Model {
+	if m.historyStore == nil {
+		m.errorMsg = "history is unavailable"
+		return m
+	}
+	if m.playlistStore == nil {
+		m.errorMsg = "playlists are unavailable"
+		return m
+	}
+
+	from, to, err := utils.ParseDateRange(value, time.Now())
+	if err != nil {
+		m.errorMsg = err.Error()
+		return m
+	}
+
+	plays := m.historyStore.PlaysBetween(from, to)
+	if len(plays) == 0 {
+		m.errorMsg = "no plays in that range"
+		return m
+	}
+
+	tracks := make([]utils.Track, 0, len(plays))
+	for i := len(plays) - 1; i >= 0; i-- {
+		tracks = append(tracks, plays[i].Track)
+	}
+
+	last := to.AddDate(0, 0, -1)
+	name := "History " + from.Format("2006-01-02")
+	if !last.Equal(from) {
+		name += " to " + last.Format("2006-01-02")
+	}
+
+	if err := m.playlistStore.CreatePlaylist(name); err != nil {
+		m.errorMsg = err.Error()
+		return m
+	}
+	if err := m.playlistStore.AddTracks(name, tracks); err != nil {
+		m.errorMsg = err.Error()
+		return m
+	}
+
+	m.currentPlaylist = name
+	return m
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/ryansantos40/go-music-player/utils"
 )
@@ -24,6 +25,12 @@ func (m Model) renderLibraryColumn() string {
 
 	case SectionArtists:
 		b.WriteString(m.renderArtistsList())
+
+	case SectionHistory:
+		b.WriteString(m.renderHistoryList())
+
+	case SectionStats:
+		b.WriteString(m.renderStatsList())
 	}
 
 	return b.String()
@@ -37,6 +44,8 @@ func (m Model) renderLibraryTabs() string {
 		{"Playlists", SectionPlaylists},
 		{"Albums", SectionAlbums},
 		{"Artists", SectionArtists},
+		{"History", SectionHistory},
+		{"Stats", SectionStats},
 	}
 
 	var parts []string
@@ -53,10 +62,19 @@ func (m Model) renderLibraryTabs() string {
 	return strings.Join(parts, " ")
 }
 
+// playlistNames lists the saved playlists, or none when the playlist store
+// couldn't be opened.
+func (m Model) playlistNames() []string {
+	if m.playlistStore == nil {
+		return nil
+	}
+	return m.playlistStore.ListPlaylists()
+}
+
 func (m Model) renderPlaylistsList() string {
 	var b strings.Builder
 
-	playlists := m.playlistStore.ListPlaylists()
+	playlists := m.playlistNames()
 
 	if len(playlists) == 0 {
 		b.WriteString(subtleStyle.Render("No playlists yet"))
@@ -70,13 +88,7 @@ func (m Model) renderPlaylistsList() string {
 
 	for i := start; i < end; i++ {
 		name := playlists[i]
-		trackCount := 0
-
-		if playlist, err := m.playlistStore.GetPlaylist(name); err == nil {
-			trackCount = len(playlist.Tracks)
-		}
-
-		line := fmt.Sprintf("%s (%d tracks)", name, trackCount)
+		line := fmt.Sprintf("%s (%d tracks, %s)", name, m.totals.playlistTracks[name], formatTime(m.totals.playlists[name]))
 
 		switch {
 		case i == m.playlistIndex && m.focusedColumn == 0:
@@ -107,7 +119,7 @@ func (m Model) renderAlbumsList() string {
 
 	for i := start; i < end; i++ {
 		album := albums[i]
-		line := fmt.Sprintf("%s - %s (%d)", album.Title, album.Artist, len(album.Tracks))
+		line := fmt.Sprintf("%s - %s (%d · %s)", album.Title, album.Artist, len(album.Tracks), formatTime(m.totals.albums[album.Key]))
 
 		if i == m.albumIndex && m.librarySection == SectionAlbums && m.focusedColumn == 0 {
 			b.WriteString(selectedStyle.Render("> " + line))
@@ -140,7 +152,7 @@ func (m Model) renderArtistsList() string {
 
 	for i := start; i < end; i++ {
 		artist := artists[i]
-		line := fmt.Sprintf("%s (%d)", artist.Name, len(artist.Tracks))
+		line := fmt.Sprintf("%s (%d · %s)", artist.Name, len(artist.Tracks), formatTime(m.totals.artists[artist.Key]))
 
 		if i == m.artistIndex && m.librarySection == SectionArtists && m.focusedColumn == 0 {
 			b.WriteString(selectedStyle.Render("> " + line))
@@ -157,6 +169,10 @@ func (m Model) renderArtistsList() string {
 }
 
 func (m Model) renderTracksColumn() string {
+	if m.showQueue {
+		return m.renderQueueColumn()
+	}
+
 	var b strings.Builder
 
 	title := "--- [ ALL TRACKS ] ---"
@@ -167,6 +183,10 @@ func (m Model) renderTracksColumn() string {
 		title = fmt.Sprintf("--- [ ALBUM: %s ] ---", m.currentFilter.Label)
 	case FilterArtist:
 		title = fmt.Sprintf("--- [ ARTIST: %s ] ---", m.currentFilter.Label)
+	case FilterHistory:
+		title = fmt.Sprintf("--- [ HISTORY: %s ] ---", m.currentFilter.Label)
+	case FilterStats:
+		title = fmt.Sprintf("--- [ STATS: %s ] ---", m.currentFilter.Label)
 	}
 
 	b.WriteString(sectionTitleStyle.Width(m.width/3 - 4).Render(title))
@@ -174,6 +194,11 @@ func (m Model) renderTracksColumn() string {
 
 	tracks := m.getFilteredTracks()
 
+	var plays []utils.HistoryEntry
+	if m.currentFilter.Type == FilterHistory {
+		plays = m.historyPlays(m.currentFilter.Key)
+	}
+
 	if len(tracks) == 0 {
 		b.WriteString(subtleStyle.Render("No tracks"))
 		return b.String()
@@ -193,6 +218,16 @@ func (m Model) renderTracksColumn() string {
 	for i := start; i < end; i++ {
 		track := tracks[i]
 		line := fmt.Sprintf("%d. %s - %s", i+1, track.Title, track.Artist)
+		if i < len(plays) {
+			line = formatHistoryPlay(plays[i])
+		} else {
+			if track.Duration > 0 {
+				line += " · " + formatTime(track.Duration)
+			}
+			if stats := m.formatTrackStats(track); stats != "" {
+				line += " · " + stats
+			}
+		}
 
 		switch {
 		case i == m.selectedIndex && m.focusedColumn == 1:
@@ -243,13 +278,19 @@ func (m *Model) syncLibrarySelection() {
 
 	case SectionArtists:
 		m.applyArtistSelection()
+
+	case SectionHistory:
+		m.applyHistorySelection()
+
+	case SectionStats:
+		m.applyStatsSelection()
 	}
 }
 
 func (m *Model) navigateLibrary(delta int) {
 	switch m.librarySection {
 	case SectionPlaylists:
-		playlists := m.playlistStore.ListPlaylists()
+		playlists := m.playlistNames()
 		if len(playlists) == 0 {
 			return
 		}
@@ -269,6 +310,16 @@ func (m *Model) navigateLibrary(delta int) {
 		}
 		m.artistIndex = (m.artistIndex + delta + len(artists)) % len(artists)
 		m.applyArtistSelection()
+	case SectionHistory:
+		days := m.historyDays
+		if len(days) == 0 {
+			return
+		}
+		m.historyIndex = (m.historyIndex + delta + len(days)) % len(days)
+		m.applyHistorySelection()
+	case SectionStats:
+		m.statsIndex = (m.statsIndex + delta + len(statsLists)) % len(statsLists)
+		m.applyStatsSelection()
 	}
 }
 
@@ -286,27 +337,19 @@ func (m *Model) handleTrackSelection() {
 		return
 	}
 
-	switch m.currentFilter.Type {
-	case FilterPlaylist:
-		if playlist, err := m.playlistStore.GetPlaylist(m.currentFilter.Key); err == nil {
-			m.player = utils.NewPlayer(playlist.Tracks)
-			_ = m.player.Skip(m.selectedIndex)
-		}
-	case FilterAlbum, FilterArtist:
+	if m.player == nil {
 		m.player = utils.NewPlayer(tracks)
-		_ = m.player.Skip(m.selectedIndex)
-	default:
-		if m.player == nil {
-			m.player = utils.NewPlayer(m.tracks)
-		}
-		_ = m.player.Skip(m.selectedIndex)
 	}
+	_ = m.player.PlayContext(tracks, m.selectedIndex)
+	filter := m.currentFilter
+	m.playingContext = filter.Label
+	m.playingFilter = &filter
 
-	m.lastTrackIdx = m.selectedIndex
+	m.lastTrackIdx = m.player.GetCurrentIndex()
 }
 
 func (m *Model) applyPlaylistSelection() {
-	playlists := m.playlistStore.ListPlaylists()
+	playlists := m.playlistNames()
 	if len(playlists) == 0 {
 		m.setFilterAll()
 		return
@@ -377,6 +420,149 @@ func (m *Model) applyArtistSelection() {
 	m.selectedIndex = 0
 }
 
+// refreshLibraryTracks swaps in a rescanned library, keeping the current view
+// when it still has tracks. Playback carries on over the new track list.
+func (m *Model) refreshLibraryTracks(tracks []utils.Track) {
+	m.tracks = tracks
+	m.libraryVersion++
+	if m.statsStore != nil {
+		m.statsStore.AddTracks(tracks, time.Now())
+	}
+	m.syncPlayingContext()
+
+	filtered, ok := m.filterTracks(m.currentFilter)
+	if (!ok || len(filtered) == 0) && m.currentFilter.Type != FilterAll {
+		m.setFilterAll()
+		filtered = m.tracks
+	}
+	if m.selectedIndex >= len(filtered) {
+		m.selectedIndex = 0
+	}
+}
+
+type totalsCache struct {
+	libraryVersion  int
+	playlistVersion int
+	albums          map[string]time.Duration
+	artists         map[string]time.Duration
+	playlists       map[string]time.Duration
+	playlistTracks  map[string]int
+}
+
+// refreshTotals rebuilds the totals when the library or the playlists have
+// changed since they were built.
+func (m Model) refreshTotals() Model {
+	playlistVersion := 0
+	if m.playlistStore != nil {
+		playlistVersion = m.playlistStore.Version()
+	}
+
+	cache := m.totals
+	if cache.albums != nil && cache.libraryVersion == m.libraryVersion && cache.playlistVersion == playlistVersion {
+		return m
+	}
+
+	m.totals = m.buildTotalsCache()
+	m.totals.libraryVersion = m.libraryVersion
+	m.totals.playlistVersion = playlistVersion
+	return m
+}
+
+func (m Model) buildTotalsCache() totalsCache {
+	cache := totalsCache{
+		albums:         make(map[string]time.Duration),
+		artists:        make(map[string]time.Duration),
+		playlists:      make(map[string]time.Duration),
+		playlistTracks: make(map[string]int),
+	}
+
+	library := make(map[string]time.Duration, len(m.tracks))
+	for _, track := range m.tracks {
+		library[track.Path] = track.Duration
+	}
+
+	for _, album := range m.buildAlbumGroups() {
+		cache.albums[album.Key] = totalDuration(album.Tracks, library)
+	}
+	for _, artist := range m.buildArtistGroups() {
+		cache.artists[artist.Key] = totalDuration(artist.Tracks, library)
+	}
+
+	for _, name := range m.playlistNames() {
+		if playlist, err := m.playlistStore.GetPlaylist(name); err == nil {
+			cache.playlists[name] = totalDuration(playlist.Tracks, library)
+			cache.playlistTracks[name] = len(playlist.Tracks)
+		}
+	}
+	return cache
+}
+
+// totalDuration adds up the length of tracks. Tracks saved in playlists before
+// durations were scanned are looked up in library instead.
+func totalDuration(tracks []utils.Track, library map[string]time.Duration) time.Duration {
+	var total time.Duration
+	for _, track := range tracks {
+		if track.Duration > 0 {
+			total += track.Duration
+		} else {
+			total += library[track.Path]
+		}
+	}
+	return total
+}
+
+// fromLibrary reports whether a filter's tracks are taken from the library,
+// rather than being a list of plays.
+func (t FilterType) fromLibrary() bool {
+	switch t {
+	case FilterAll, FilterAlbum, FilterArtist, FilterPlaylist:
+		return true
+	}
+	return false
+}
+
+// syncPlayingContext brings the player's context up to date with the library
+// when it was taken from it: the whole library, an album, an artist or a
+// playlist, whose tracks are matched to their library copies. History and
+// statistics lists stay as they were when played.
+func (m *Model) syncPlayingContext() {
+	if m.player == nil || m.playingFilter == nil {
+		return
+	}
+
+	filter := *m.playingFilter
+	if !filter.Type.fromLibrary() {
+		return
+	}
+
+	tracks, ok := m.filterTracks(filter)
+	if !ok {
+		tracks = nil
+	}
+	if filter.Type == FilterPlaylist {
+		library := make(map[string]utils.Track, len(m.tracks))
+		for _, track := range m.tracks {
+			library[track.Path] = track
+		}
+		copies := make([]utils.Track, len(tracks))
+		for i, track := range tracks {
+			if fresh, ok := library[track.Path]; ok {
+				track = fresh
+			}
+			copies[i] = track
+		}
+		tracks = copies
+	}
+	m.player.ReplaceTracks(tracks)
+}
+
+// playingAllTracks reports whether the player's context is the whole library.
+// It is false when that is unknown, as for sessions saved before the context's
+// filter was.
+func (m Model) playingAllTracks() bool {
+	return m.playingFilter != nil && m.playingFilter.Type == FilterAll
+}
+
 func (m *Model) setFilterAll() {
 	m.currentPlaylist = ""
 	m.currentFilter = TrackFilter{
@@ -388,25 +574,43 @@ func (m *Model) setFilterAll() {
 }
 
 func (m Model) getFilteredTracks() []utils.Track {
-	switch m.currentFilter.Type {
+	tracks, _ := m.filterTracks(m.currentFilter)
+	return tracks
+}
+
+// filterTracks returns the tracks filter selects. When what it selects is gone
+// it returns the whole library and false.
+func (m Model) filterTracks(filter TrackFilter) ([]utils.Track, bool) {
+	switch filter.Type {
+	case FilterAll:
+		return m.tracks, true
 	case FilterPlaylist:
-		if playlist, err := m.playlistStore.GetPlaylist(m.currentFilter.Key); err == nil {
-			return playlist.Tracks
+		if m.playlistStore == nil {
+			break
+		}
+		if playlist, err := m.playlistStore.GetPlaylist(filter.Key); err == nil {
+			return playlist.Tracks, true
 		}
 	case FilterAlbum:
 		for _, album := range m.buildAlbumGroups() {
-			if album.Key == m.currentFilter.Key {
-				return album.Tracks
+			if album.Key == filter.Key {
+				return album.Tracks, true
 			}
 		}
 	case FilterArtist:
 		for _, artist := range m.buildArtistGroups() {
-			if artist.Key == m.currentFilter.Key {
-				return artist.Tracks
+			if artist.Key == filter.Key {
+				return artist.Tracks, true
 			}
 		}
+	case FilterHistory:
+		if plays := m.historyPlays(filter.Key); len(plays) > 0 {
+			return historyTracks(plays), true
+		}
+	case FilterStats:
+		return m.statsTracks(filter.Key), true
 	}
-	return m.tracks
+	return m.tracks, false
 }
 
 func (m Model) buildAlbumGroups() []AlbumGroup {
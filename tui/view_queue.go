@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (m Model) renderQueueColumn() string {
+	var b strings.Builder
+
+	var queueLen int
+	if m.player != nil {
+		queueLen = len(m.player.GetQueue())
+	}
+
+	title := fmt.Sprintf("--- [ QUEUE (%d) ] ---", queueLen)
+	b.WriteString(sectionTitleStyle.Width(m.width/3 - 4).Render(title))
+	b.WriteString("\n\n")
+
+	if m.player == nil || queueLen == 0 {
+		b.WriteString(subtleStyle.Render("Queue is empty"))
+		b.WriteString("\n\n")
+		b.WriteString(subtleStyle.Render("Press 'N' to play next, 'B' to add to queue"))
+		return b.String()
+	}
+
+	queue := m.player.GetQueue()
+
+	maxVisible := m.height - 18
+	start, end := clampWindow(m.queueIndex, len(queue), maxVisible)
+
+	for i := start; i < end; i++ {
+		track := queue[i]
+		line := fmt.Sprintf("%d. %s - %s", i+1, track.Title, track.Artist)
+
+		if i == m.queueIndex && m.focusedColumn == 1 {
+			b.WriteString(selectedStyle.Render("> " + line))
+		} else {
+			b.WriteString(subtleStyle.Render("  " + line))
+		}
+
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(subtleStyle.Render("Then continues with: " + m.playingContext))
+
+	return b.String()
+}
+
+func (m *Model) navigateQueue(delta int) {
+	if m.player == nil {
+		return
+	}
+
+	queue := m.player.GetQueue()
+	if len(queue) == 0 {
+		return
+	}
+	m.queueIndex = (m.queueIndex + delta + len(queue)) % len(queue)
+}
+
+func (m *Model) handleQueueSelection() {
+	if err := m.player.SkipToQueued(m.queueIndex); err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+
+	if m.queueIndex > 0 && m.queueIndex >= len(m.player.GetQueue()) {
+		m.queueIndex--
+	}
+}
+
+func (m *Model) enqueueSelection(next bool) {
+	tracks := m.getFilteredTracks()
+	if len(tracks) == 0 || m.selectedIndex >= len(tracks) {
+		return
+	}
+
+	track := tracks[m.selectedIndex]
+	if next {
+		m.player.PlayNext(track)
+	} else {
+		m.player.Enqueue(track)
+	}
+}
+
+func (m *Model) moveQueueSelection(delta int) {
+	target := m.queueIndex + delta
+	if err := m.player.MoveInQueue(m.queueIndex, target); err != nil {
+		return
+	}
+	m.queueIndex = target
+}
@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+var statsLists = []StatsList{
+	{Key: "most-played", Label: "Most Played"},
+	{Key: "month", Label: "Most Played This Month"},
+	{Key: "recent", Label: "Recently Played"},
+	{Key: "skipped", Label: "Most Skipped"},
+	{Key: "never", Label: "Never Played"},
+	{Key: "added", Label: "Recently Added"},
+}
+
+func (m Model) renderStatsList() string {
+	var b strings.Builder
+
+	if m.statsStore == nil {
+		b.WriteString(subtleStyle.Render("Statistics are unavailable"))
+		return b.String()
+	}
+
+	for i, list := range statsLists {
+		if i == m.statsIndex && m.librarySection == SectionStats && m.focusedColumn == 0 {
+			b.WriteString(selectedStyle.Render("> " + list.Label))
+		} else if m.currentFilter.Type == FilterStats && m.currentFilter.Key == list.Key {
+			b.WriteString(statusStyle.Render("* " + list.Label))
+		} else {
+			b.WriteString(subtleStyle.Render("  " + list.Label))
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func (m *Model) applyStatsSelection() {
+	if m.statsStore == nil {
+		m.setFilterAll()
+		return
+	}
+
+	if m.statsIndex >= len(statsLists) || m.statsIndex < 0 {
+		m.statsIndex = 0
+	}
+
+	list := statsLists[m.statsIndex]
+	m.currentPlaylist = ""
+	m.currentFilter = TrackFilter{
+		Type:  FilterStats,
+		Key:   list.Key,
+		Label: list.Label,
+	}
+	m.selectedIndex = 0
+	*m = m.refreshStats()
+}
+
+type statsCache struct {
+	key            string
+	statsVersion   int
+	historyVersion int
+	libraryVersion int
+	tracks         []utils.Track
+	stats          map[string]utils.TrackStats
+	monthPlays     map[string]int
+}
+
+// refreshStats rebuilds the statistics list when another list is selected or
+// the statistics, history or library have changed since it was built.
+func (m Model) refreshStats() Model {
+	if m.statsStore == nil || m.currentFilter.Type != FilterStats {
+		return m
+	}
+
+	historyVersion := 0
+	if m.historyStore != nil {
+		historyVersion = m.historyStore.Version()
+	}
+	statsVersion := m.statsStore.Version()
+
+	cache := m.statsCache
+	if cache.stats != nil && cache.key == m.currentFilter.Key && cache.statsVersion == statsVersion &&
+		cache.historyVersion == historyVersion && cache.libraryVersion == m.libraryVersion {
+		return m
+	}
+
+	m.statsCache = m.buildStatsCache(m.currentFilter.Key)
+	m.statsCache.statsVersion = statsVersion
+	m.statsCache.historyVersion = historyVersion
+	m.statsCache.libraryVersion = m.libraryVersion
+	return m
+}
+
+func (m Model) buildStatsCache(key string) statsCache {
+	cache := statsCache{
+		key:   key,
+		stats: make(map[string]utils.TrackStats, len(m.tracks)),
+	}
+	for _, track := range m.tracks {
+		cache.stats[track.Path] = m.statsStore.Get(track)
+	}
+	stats := cache.stats
+
+	if cache.key == "month" {
+		cache.monthPlays = m.monthPlayCounts()
+	}
+	monthPlays := cache.monthPlays
+
+	var keep func(utils.TrackStats, utils.Track) bool
+	var less func(a, b utils.Track) bool
+
+	switch cache.key {
+	case "most-played":
+		keep = func(s utils.TrackStats, _ utils.Track) bool { return s.PlayCount > 0 }
+		less = func(a, b utils.Track) bool { return stats[a.Path].PlayCount > stats[b.Path].PlayCount }
+	case "month":
+		keep = func(_ utils.TrackStats, t utils.Track) bool { return monthPlays[utils.StatsKey(t)] > 0 }
+		less = func(a, b utils.Track) bool {
+			return monthPlays[utils.StatsKey(a)] > monthPlays[utils.StatsKey(b)]
+		}
+	case "recent":
+		keep = func(s utils.TrackStats, _ utils.Track) bool { return !s.LastPlayed.IsZero() }
+		less = func(a, b utils.Track) bool { return stats[a.Path].LastPlayed.After(stats[b.Path].LastPlayed) }
+	case "skipped":
+		keep = func(s utils.TrackStats, _ utils.Track) bool { return s.SkipCount > 0 }
+		less = func(a, b utils.Track) bool { return stats[a.Path].SkipCount > stats[b.Path].SkipCount }
+	case "never":
+		keep = func(s utils.TrackStats, _ utils.Track) bool { return s.PlayCount == 0 }
+	case "added":
+		keep = func(utils.TrackStats, utils.Track) bool { return true }
+		less = func(a, b utils.Track) bool { return stats[a.Path].FirstAdded.After(stats[b.Path].FirstAdded) }
+	default:
+		return cache
+	}
+
+	for _, track := range m.tracks {
+		if keep(stats[track.Path], track) {
+			cache.tracks = append(cache.tracks, track)
+		}
+	}
+
+	if less != nil {
+		sort.SliceStable(cache.tracks, func(i, j int) bool { return less(cache.tracks[i], cache.tracks[j]) })
+	}
+	return cache
+}
+
+func (m Model) statsTracks(key string) []utils.Track {
+	if m.statsCache.stats != nil && m.statsCache.key == key {
+		return m.statsCache.tracks
+	}
+	if m.statsStore == nil {
+		return nil
+	}
+	return m.buildStatsCache(key).tracks
+}
+
+func (m Model) formatTrackStats(track utils.Track) string {
+	if m.statsStore == nil {
+		return ""
+	}
+	// Outside the statistics lists only the visible rows are looked up.
+	stats, ok := m.statsCache.stats[track.Path]
+	if !ok || m.currentFilter.Type != FilterStats {
+		stats = m.statsStore.Get(track)
+	}
+
+	var parts []string
+	if m.currentFilter.Type == FilterStats {
+		switch {
+		case m.currentFilter.Key == "month":
+			parts = append(parts, fmt.Sprintf("%d this month", m.statsCache.monthPlays[utils.StatsKey(track)]))
+		case m.currentFilter.Key == "added" && !stats.FirstAdded.IsZero():
+			parts = append(parts, "added "+formatAgo(stats.FirstAdded))
+		}
+	}
+	if stats.PlayCount > 0 {
+		parts = append(parts, pluralize(stats.PlayCount, "play"))
+	}
+	if stats.SkipCount > 0 {
+		parts = append(parts, pluralize(stats.SkipCount, "skip"))
+	}
+	if !stats.LastPlayed.IsZero() {
+		parts = append(parts, formatAgo(stats.LastPlayed))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+func (m Model) monthPlayCounts() map[string]int {
+	if m.historyStore == nil {
+		return nil
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return m.historyStore.PlayCountsBetween(from, from.AddDate(0, 1, 0))
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+func formatAgo(t time.Time) string {
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	case elapsed < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	default:
+		return t.Local().Format("2006-01-02")
+	}
+}
@@ -8,6 +8,14 @@ import (
 	"github.com/ryansantos40/go-music-player/utils"
 )
 
+const spectrumMaxRows = 12
+
+var spectrumBlocks = []rune("▁▂▃▄▅▆▇█")
+
+func spectrumBands(width int) int {
+	return (width/3 - 4) / 2
+}
+
 func (m Model) renderVisualizerColumn() string {
 	var b strings.Builder
 
@@ -21,7 +29,11 @@ func (m Model) renderVisualizerColumn() string {
 	infoHeight := 3
 
 	artHeight := availableHeight - infoHeight - 1
-	albumArt := m.getAlbumArtBraille(artHeight)
+	albumArt := ""
+	if !m.showSpectrum {
+		albumArt = m.getAlbumArtBraille(artHeight)
+	}
+	artLinesCount := len(strings.Split(albumArt, "\n"))
 
 	if albumArt != "" {
 		artLines := strings.Split(albumArt, "\n")
@@ -47,10 +59,11 @@ func (m Model) renderVisualizerColumn() string {
 			b.WriteString("\n")
 		}
 	} else {
-		b.WriteString(m.renderFallbackVisualizer(artHeight))
+		spectrum := m.renderSpectrum(artHeight)
+		b.WriteString(spectrum)
+		artLinesCount = strings.Count(spectrum, "\n")
 	}
 
-	artLinesCount := len(strings.Split(albumArt, "\n"))
 	remainingSpace := availableHeight - artLinesCount - infoHeight
 
 	emptyLine := lipgloss.NewStyle().
@@ -93,58 +106,6 @@ func (m Model) renderVisualizerColumn() string {
 	return b.String()
 }
 
-func (m Model) generateVisualizer() string {
-	if m.player == nil || !m.player.IsPlaying() {
-		return `
-   .  |..  |..|||   ||||   
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   `
-	}
-
-	progress := m.player.GetProgress()
-	frame := int(progress*10) % 5
-
-	frames := []string{
-		`
-   |   ||   |||    ||||    
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   `,
-		`
-   .  |..  |..|||   ||||   
-   |   ||   |||    ||||    
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   
- ...|...||...|||....|||....`,
-		`
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   
-   |   ||   |||    ||||    
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   `,
-		`
-   .  |..  |..|||   ||||   
- ...|...||...|||....|||....
-   |   ||   |||    ||||    
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   `,
-		`
- ...|...||...|||....|||....
-   .  |..  |..|||   ||||   
- ...|...||...|||....|||....
-   |   ||   |||    ||||    
-   .  |..  |..|||   ||||   `,
-	}
-
-	if frame < len(frames) {
-		return frames[frame]
-	}
-	return frames[0]
-}
-
 func (m Model) getAlbumArtBraille(height int) string {
 	if m.player == nil {
 		return ""
@@ -164,7 +125,7 @@ func (m Model) getAlbumArtBraille(height int) string {
 		width = squareWidth
 	}
 
-	art := utils.GetAlbumArtHalfBlocksColored(currentTrack.Path, width, height)
+	art := utils.GetAlbumArtHalfBlocksColored(currentTrack.AudioFile(), width, height)
 
 	if art == "" {
 		return ""
@@ -176,14 +137,21 @@ func (m Model) getAlbumArtBraille(height int) string {
 	return art
 }
 
-func (m Model) renderFallbackVisualizer(height int) string {
+func (m Model) renderSpectrum(height int) string {
 	var b strings.Builder
 
-	visualizerHeight := 7
-	topPadding := (height - visualizerHeight) / 2
+	width := m.width/3 - 4
+	rows := height - 2
+	if rows > spectrumMaxRows {
+		rows = spectrumMaxRows
+	}
+	if rows < 1 || m.spectrum == nil {
+		return ""
+	}
 
+	topPadding := (height - rows) / 2
 	emptyLine := lipgloss.NewStyle().
-		Width(m.width/3 - 4).
+		Width(width).
 		Background(colorBg).
 		Render("")
 
@@ -192,19 +160,41 @@ func (m Model) renderFallbackVisualizer(height int) string {
 		b.WriteString("\n")
 	}
 
-	visualizer := m.generateVisualizer()
-	visualizerLines := strings.Split(visualizer, "\n")
-	for _, line := range visualizerLines {
-		if line != "" {
-			centered := lipgloss.NewStyle().
-				Width(m.width/3 - 4).
-				Align(lipgloss.Center).
-				Foreground(colorAccent).
-				Background(colorBg).
-				Render(line)
-			b.WriteString(centered)
-			b.WriteString("\n")
+	levels := m.spectrum.Levels()
+	peaks := m.spectrum.Peaks()
+	barStyle := lipgloss.NewStyle().Foreground(colorAccent).Background(colorBg)
+	peakStyle := lipgloss.NewStyle().Foreground(colorLoop).Background(colorBg)
+
+	for row := rows - 1; row >= 0; row-- {
+		var line strings.Builder
+		for i, level := range levels {
+			filled := level * float64(rows)
+			peakRow := int(peaks[i] * float64(rows))
+			if peakRow >= rows {
+				peakRow = rows - 1
+			}
+
+			switch {
+			case filled >= float64(row+1):
+				line.WriteString(barStyle.Render("█"))
+			case filled > float64(row):
+				fraction := filled - float64(row)
+				line.WriteString(barStyle.Render(string(spectrumBlocks[int(fraction*float64(len(spectrumBlocks)-1))])))
+			case row == peakRow && peaks[i] > 0:
+				line.WriteString(peakStyle.Render("▔"))
+			default:
+				line.WriteString(" ")
+			}
+			line.WriteString(" ")
 		}
+
+		centered := lipgloss.NewStyle().
+			Width(width).
+			Align(lipgloss.Center).
+			Background(colorBg).
+			Render(line.String())
+		b.WriteString(centered)
+		b.WriteString("\n")
 	}
 
 	return b.String()
@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+const waveformFloorDB = -48.0
+
+var waveformBlocks = []rune("▁▂▃▄▅▆▇█")
+
+type waveformMsg struct {
+	path     string
+	waveform *utils.Waveform
+	err      error
+}
+
+func loadWaveform(track utils.Track) tea.Cmd {
+	return func() tea.Msg {
+		waveform, err := utils.LoadWaveform(track)
+		return waveformMsg{path: track.Path, waveform: waveform, err: err}
+	}
+}
+
+// requestWaveform starts loading the current track's waveform when the
+// waveform bar is on and it isn't loaded or loading already. waveformPath is
+// only set once the load succeeds, so a failed track is tried again the next
+// time it starts.
+func (m Model) requestWaveform() (Model, tea.Cmd) {
+	if !m.showWaveform || m.player == nil {
+		return m, nil
+	}
+
+	track := m.player.GetCurrentTrack()
+	if track.Path == "" || track.Path == m.waveformPath || track.Path == m.waveformLoading {
+		return m, nil
+	}
+
+	m.waveformLoading = track.Path
+	m.waveformPath = ""
+	m.waveform = nil
+	return m, loadWaveform(track)
+}
+
+func (m Model) handleWaveform(msg waveformMsg) Model {
+	if msg.path != m.waveformLoading {
+		return m
+	}
+
+	m.waveformLoading = ""
+	if msg.err != nil {
+		m.errorMsg = "Waveform: " + msg.err.Error()
+		return m
+	}
+	m.waveformPath = msg.path
+	m.waveform = msg.waveform
+	return m
+}
+
+func waveformLevel(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(amplitude)
+	return math.Max(0, math.Min(1, 1-db/waveformFloorDB))
+}
+
+// renderWaveformBar draws each column's loudness as a block whose height
+// follows the RMS level. Columns with audible peaks never drop below the
+// smallest block, so only real silence is left blank.
+func (m Model) renderWaveformBar(percent float64) string {
+	width := m.progressBar.Width
+	if width <= 0 || m.waveform == nil {
+		return ""
+	}
+
+	peaks, rms := m.waveform.Columns(width)
+	played := int(percent * float64(width))
+
+	playedStyle := lipgloss.NewStyle().Foreground(colorAccent).Background(colorBg)
+	remainingStyle := lipgloss.NewStyle().Foreground(colorSubtle).Background(colorBg)
+
+	var playedPart, remainingPart strings.Builder
+	for i := 0; i < width; i++ {
+		char := ' '
+		if waveformLevel(peaks[i]) > 0 {
+			index := int(waveformLevel(rms[i]) * float64(len(waveformBlocks)-1))
+			char = waveformBlocks[index]
+		}
+
+		if i < played {
+			playedPart.WriteRune(char)
+		} else {
+			remainingPart.WriteRune(char)
+		}
+	}
+
+	return playedStyle.Render(playedPart.String()) + remainingStyle.Render(remainingPart.String())
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ryansantos40/go-music-player/utils"
+)
+
+type libraryChangedMsg struct {
+	dir string
+}
+
+type libraryWatchErrorMsg struct {
+	dir string
+	err error
+}
+
+// watchLibrary starts watching dir for changes, replacing the watcher of a
+// previous library.
+func (m Model) watchLibrary(dir string) (Model, tea.Cmd) {
+	if m.watcher != nil {
+		if m.watcher.Root() == dir {
+			return m, nil
+		}
+		m.watcher.Close()
+		m.watcher = nil
+	}
+
+	watcher, err := utils.WatchLibrary(dir, m.settings.LibraryPollInterval())
+	if err != nil {
+		m.errorMsg = "Not watching library: " + err.Error()
+		return m, nil
+	}
+
+	m.watcher = watcher
+	return m, waitForLibraryChange(watcher)
+}
+
+func waitForLibraryChange(watcher *utils.LibraryWatcher) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case _, ok := <-watcher.Changes():
+			if !ok {
+				return nil
+			}
+			return libraryChangedMsg{dir: watcher.Root()}
+		case err := <-watcher.Errors():
+			return libraryWatchErrorMsg{dir: watcher.Root(), err: err}
+		}
+	}
+}
+
+// startLibraryRefresh rescans the library against the current index. Changes
+// that arrive while a rescan is running are picked up by one more rescan when
+// it finishes.
+func (m Model) startLibraryRefresh() (Model, tea.Cmd) {
+	if m.refreshing {
+		m.refreshPending = true
+		return m, nil
+	}
+
+	m.refreshing = true
+	m.refreshPending = false
+	return m, refreshLibrary(m.libraryRoot, m.libraryIndex, m.settings.ScanWorkers)
+}
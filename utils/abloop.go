@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+type ABLoop struct {
+	Start    time.Duration
+	End      time.Duration
+	HasStart bool
+	Active   bool
+}
+
+// loopStreamer sits between the decoder and the resampler so the jump from B
+// back to A lands on exact source samples without a gap.
+type loopStreamer struct {
+	source *trackSource
+}
+
+func (l *loopStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	s := l.source
+	if !s.looping {
+		return s.streamer.Stream(samples)
+	}
+
+	for n < len(samples) {
+		pos := s.streamer.Position()
+		if pos >= s.loopEnd {
+			if err := s.streamer.Seek(s.loopStart); err != nil {
+				return n, n > 0
+			}
+			pos = s.loopStart
+		}
+
+		chunk := samples[n:]
+		if left := s.loopEnd - pos; len(chunk) > left {
+			chunk = chunk[:left]
+		}
+
+		sn, sok := s.streamer.Stream(chunk)
+		n += sn
+		if !sok || sn == 0 {
+			return n, n > 0
+		}
+	}
+	return n, true
+}
+
+func (l *loopStreamer) Err() error {
+	return l.source.streamer.Err()
+}
+
+// audiblePosition is the position of the sample being heard, which trails the
+// decoder by what the time stretcher and the output have buffered. It is
+// called with both locks held.
+func (p *Player) audiblePosition() int {
+	buffered := float64(p.speedCtrl.buffered()) + float64(p.outputRate.N(p.output.Latency()))*p.speedCtrl.speed
+	position := p.source.streamer.Position() - int(buffered*float64(p.source.format.SampleRate)/float64(p.outputRate))
+	return max(position, 0)
+}
+
+func (p *Player) SetLoopStart() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.canSeek() {
+		return fmt.Errorf("no track is currently playing")
+	}
+
+	p.output.Lock()
+	defer p.output.Unlock()
+
+	p.source.loopStart = p.audiblePosition()
+	p.source.loopEnd = 0
+	p.source.hasLoopStart = true
+	p.source.looping = false
+	return nil
+}
+
+func (p *Player) SetLoopEnd() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.canSeek() {
+		return fmt.Errorf("no track is currently playing")
+	}
+
+	p.output.Lock()
+	defer p.output.Unlock()
+
+	if !p.source.hasLoopStart {
+		return fmt.Errorf("loop start is not set")
+	}
+
+	end := p.audiblePosition()
+	if end <= p.source.loopStart {
+		return fmt.Errorf("loop end must be after loop start")
+	}
+
+	p.source.loopEnd = end
+	p.source.looping = true
+	p.gapless.rewindNext()
+	return nil
+}
+
+func (p *Player) ClearLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.source == nil {
+		return
+	}
+
+	p.output.Lock()
+	p.source.hasLoopStart = false
+	p.source.looping = false
+	p.output.Unlock()
+}
+
+func (p *Player) CycleLoop() error {
+	loop := p.GetLoop()
+
+	switch {
+	case loop.Active:
+		p.ClearLoop()
+		return nil
+	case loop.HasStart:
+		return p.SetLoopEnd()
+	default:
+		return p.SetLoopStart()
+	}
+}
+
+func (p *Player) GetLoop() ABLoop {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.source == nil {
+		return ABLoop{}
+	}
+
+	p.output.Lock()
+	defer p.output.Unlock()
+
+	if !p.source.hasLoopStart {
+		return ABLoop{}
+	}
+
+	return ABLoop{
+		Start:    p.source.samplesToDuration(p.source.loopStart),
+		End:      p.source.samplesToDuration(p.source.loopEnd),
+		HasStart: true,
+		Active:   p.source.looping,
+	}
+}
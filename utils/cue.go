@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Tracks from a CUE sheet have a virtual Path.
+func (t Track) AudioFile() string {
+	if t.File != "" {
+		return t.File
+	}
+	return t.Path
+}
+
+func (t Track) isRegion() bool {
+	return t.Start > 0 || t.End > 0
+}
+
+type cueTrack struct {
+	number    int
+	file      string
+	title     string
+	performer string
+	indexes   map[int]time.Duration
+	rem       map[string]interface{}
+}
+
+func ParseCueSheet(path string) ([]Track, error) {
+	tracks, _, err := parseCueSheet(path)
+	return tracks, err
+}
+
+// missing lists FILE entries that could not be found, without extensions.
+func parseCueSheet(path string) ([]Track, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var album, albumPerformer, file string
+	var missing []string
+	albumRem := make(map[string]interface{})
+	var entries []*cueTrack
+	var current *cueTrack
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := splitCueLine(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if len(fields) < 2 {
+				return nil, nil, fmt.Errorf("%s:%d: FILE without a name", filepath.Base(path), line)
+			}
+			file = resolveCueFile(filepath.Dir(path), fields[1])
+			if file == "" {
+				name := filepath.Join(filepath.Dir(path), fields[1])
+				missing = append(missing, strings.TrimSuffix(name, filepath.Ext(name)))
+			}
+
+		case "TRACK":
+			if len(fields) < 3 {
+				return nil, nil, fmt.Errorf("%s:%d: malformed TRACK", filepath.Base(path), line)
+			}
+			current = nil
+			if !strings.EqualFold(fields[2], "AUDIO") {
+				continue
+			}
+			number, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: invalid track number %q", filepath.Base(path), line, fields[1])
+			}
+			current = &cueTrack{
+				number:  number,
+				file:    file,
+				indexes: make(map[int]time.Duration),
+				rem:     make(map[string]interface{}),
+			}
+			entries = append(entries, current)
+
+		case "INDEX":
+			if current == nil || len(fields) < 3 {
+				continue
+			}
+			number, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: invalid index number %q", filepath.Base(path), line, fields[1])
+			}
+			offset, err := parseCueTime(fields[2])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: %v", filepath.Base(path), line, err)
+			}
+			current.indexes[number] = offset
+
+		case "TITLE":
+			if len(fields) < 2 {
+				continue
+			}
+			if current != nil {
+				current.title = fields[1]
+			} else {
+				album = fields[1]
+			}
+
+		case "PERFORMER":
+			if len(fields) < 2 {
+				continue
+			}
+			if current != nil {
+				current.performer = fields[1]
+			} else {
+				albumPerformer = fields[1]
+			}
+
+		case "REM":
+			if len(fields) < 3 {
+				continue
+			}
+			value := strings.Join(fields[2:], " ")
+			if current != nil {
+				current.rem[strings.ToLower(fields[1])] = value
+			} else {
+				albumRem[strings.ToLower(fields[1])] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	fileInfo := make(map[string]Track)
+	var tracks []Track
+	for i, entry := range entries {
+		start, ok := entry.indexes[1]
+		if !ok || entry.file == "" {
+			continue
+		}
+
+		base, ok := fileInfo[entry.file]
+		if !ok {
+			base, _ = extractMetadata(entry.file)
+			fileInfo[entry.file] = base
+		}
+
+		track := base
+		track.Path = fmt.Sprintf("%s#%02d", path, entry.number)
+		track.File = entry.file
+		track.Title = entry.title
+		track.Artist = entry.performer
+		track.Album = album
+		track.Start = start
+		track.End = 0
+		track.Duration = 0
+		track.Indexes = nil
+
+		if track.Title == "" {
+			track.Title = fmt.Sprintf("Track %02d", entry.number)
+		}
+		if track.Artist == "" {
+			track.Artist = albumPerformer
+		}
+		if track.Artist == "" {
+			track.Artist = base.Artist
+		}
+		if track.Album == "" {
+			track.Album = base.Album
+		}
+
+		for number := 0; number <= 99; number++ {
+			if offset, ok := entry.indexes[number]; ok {
+				track.Indexes = append(track.Indexes, offset)
+			}
+		}
+
+		if i+1 < len(entries) && entries[i+1].file == entry.file {
+			if end, ok := entries[i+1].indexes[1]; ok && end > start {
+				track.End = end
+				track.Duration = end - start
+			}
+		}
+		if track.End == 0 && base.Duration > start {
+			track.Duration = base.Duration - start
+		}
+
+		rem := make(map[string]interface{}, len(albumRem)+len(entry.rem))
+		for key, value := range albumRem {
+			rem[key] = value
+		}
+		for key, value := range entry.rem {
+			rem[key] = value
+		}
+		readReplayGain(rem, &track)
+		if date, ok := rem["date"].(string); ok && len(date) >= 4 {
+			if year, err := strconv.Atoi(date[:4]); err == nil {
+				track.Year = year
+			}
+		}
+
+		tracks = append(tracks, track)
+	}
+
+	return tracks, missing, nil
+}
+
+func splitCueLine(line string) []string {
+	var fields []string
+	var field strings.Builder
+	inQuotes, hasField := false, false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasField = true
+		case (r == ' ' || r == '\t' || r == '\r') && !inQuotes:
+			if hasField {
+				fields = append(fields, field.String())
+				field.Reset()
+				hasField = false
+			}
+		default:
+			field.WriteRune(r)
+			hasField = true
+		}
+	}
+	if hasField {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// A frame is 1/75 of a second.
+func parseCueTime(text string) (time.Duration, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid cue time %q", text)
+	}
+
+	var values [3]int
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid cue time %q", text)
+		}
+		values[i] = value
+	}
+	if values[1] >= 60 || values[2] >= 75 {
+		return 0, fmt.Errorf("invalid cue time %q", text)
+	}
+
+	return time.Duration(values[0])*time.Minute +
+		time.Duration(values[1])*time.Second +
+		time.Duration(values[2])*time.Second/75, nil
+}
+
+// Lossless first, since the sheet most likely came from a lossless rip.
+var cueFileExtensions = []string{".flac", ".wav", ".ogg", ".oga", ".mp3"}
+
+func resolveCueFile(dir, name string) string {
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil && isAudioFile(path) {
+		return path
+	}
+
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range cueFileExtensions {
+		if _, err := os.Stat(stem + ext); err == nil {
+			return stem + ext
+		}
+	}
+	return ""
+}
+
+type regionStreamer struct {
+	beep.StreamSeekCloser
+	start int
+	end   int
+}
+
+func newRegionStreamer(streamer beep.StreamSeekCloser, format beep.Format, track Track) (*regionStreamer, error) {
+	start := format.SampleRate.N(track.Start)
+	end := streamer.Len()
+	if track.End > 0 {
+		if n := format.SampleRate.N(track.End); n < end {
+			end = n
+		}
+	}
+	if start >= end {
+		return nil, fmt.Errorf("cue track starts after the end of %s", filepath.Base(track.AudioFile()))
+	}
+
+	if err := streamer.Seek(start); err != nil {
+		return nil, err
+	}
+	return &regionStreamer{StreamSeekCloser: streamer, start: start, end: end}, nil
+}
+
+func (r *regionStreamer) Stream(samples [][2]float64) (int, bool) {
+	left := r.end - r.StreamSeekCloser.Position()
+	if left <= 0 {
+		return 0, false
+	}
+	if len(samples) > left {
+		samples = samples[:left]
+	}
+	return r.StreamSeekCloser.Stream(samples)
+}
+
+func (r *regionStreamer) Len() int {
+	return r.end - r.start
+}
+
+func (r *regionStreamer) Position() int {
+	return r.StreamSeekCloser.Position() - r.start
+}
+
+func (r *regionStreamer) Seek(p int) error {
+	return r.StreamSeekCloser.Seek(r.start + p)
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testCueSheet = `REM DATE 1999
+PERFORMER "The Band"
+TITLE "Live Album"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Middle"
+    PERFORMER "Guest"
+    INDEX 00 00:00:22
+    INDEX 01 00:00:30
+  TRACK 03 AUDIO
+    TITLE "Closing"
+    INDEX 01 00:01:15
+`
+
+func TestScanDirCueSheet(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTrack(t, dir, "album.wav", 1500*time.Millisecond)
+	writeTestTrack(t, dir, "single.wav", testTrackLength)
+	if err := os.WriteFile(filepath.Join(dir, "album.cue"), []byte(testCueSheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracks, err := ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 4 {
+		t.Fatalf("scanned %d tracks, want 3 from the sheet and the single", len(tracks))
+	}
+
+	middle := tracks[1]
+	if middle.Title != "Middle" || middle.Artist != "Guest" || middle.Album != "Live Album" || middle.Year != 1999 {
+		t.Fatalf("middle track = %+v", middle)
+	}
+	if middle.File != filepath.Join(dir, "album.wav") || middle.Path == tracks[0].Path {
+		t.Fatalf("middle track path %q, file %q", middle.Path, middle.File)
+	}
+	assertDuration(t, "start", middle.Start, 400*time.Millisecond)
+	assertDuration(t, "end", middle.End, 1200*time.Millisecond)
+	if len(middle.Indexes) != 2 {
+		t.Fatalf("middle track has %d index points, want 2", len(middle.Indexes))
+	}
+	if tracks[0].Artist != "The Band" || tracks[2].End != 0 {
+		t.Fatalf("first artist %q, last end %v", tracks[0].Artist, tracks[2].End)
+	}
+}
+
+func TestResolveCueFilePrefersLossless(t *testing.T) {
+	for ext := range audioDecoders {
+		if !slices.Contains(cueFileExtensions, ext) {
+			t.Errorf("%s is missing from cueFileExtensions", ext)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"album.mp3", "album.ogg", "album.wav"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := filepath.Join(dir, "album.wav")
+	for i := 0; i < 20; i++ {
+		if got := resolveCueFile(dir, "album.ape"); got != want {
+			t.Fatalf("resolveCueFile = %q, want %q", got, want)
+		}
+	}
+	if got := resolveCueFile(dir, "album.mp3"); got != filepath.Join(dir, "album.mp3") {
+		t.Fatalf("resolveCueFile = %q, want the named file", got)
+	}
+}
+
+func TestPlayCueTracks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTrack(t, dir, "album.wav", 1500*time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "album.cue"), []byte(testCueSheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracks, err := ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := NewNullOutput(0)
+	player := NewPlayerWithOutput(tracks, output)
+	t.Cleanup(func() { player.Close() })
+	events, unsubscribe := player.Subscribe()
+	t.Cleanup(unsubscribe)
+
+	if err := player.Skip(1); err != nil {
+		t.Fatal(err)
+	}
+	started := waitForEvent(t, events, EventTrackStarted)
+	assertDuration(t, "duration", started.Duration, 800*time.Millisecond)
+	assertDuration(t, "current time", player.GetCurrentTime(), 0)
+
+	if err := player.SeekTo(700 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	assertDuration(t, "position after seeking", player.GetCurrentTime(), 700*time.Millisecond)
+
+	output.Advance(200 * time.Millisecond)
+	ended := waitForEvent(t, events, EventTrackEnded)
+	if ended.Index != 1 || !ended.Completed {
+		t.Fatalf("ended = index %d completed %v, want index 1 completed", ended.Index, ended.Completed)
+	}
+	started = waitForEvent(t, events, EventTrackStarted)
+	if started.Track.Title != "Closing" {
+		t.Fatalf("continued with %q, want Closing", started.Track.Title)
+	}
+	assertDuration(t, "current time", player.GetCurrentTime(), 100*time.Millisecond)
+	assertDuration(t, "duration", started.Duration, 300*time.Millisecond)
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/flac"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/wav"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+type decoderFunc func(f *os.File) (beep.StreamSeekCloser, beep.Format, error)
+
+var audioDecoders = map[string]decoderFunc{
+	".mp3": func(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
+		return mp3.Decode(f)
+	},
+	".wav": func(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
+		return wav.Decode(f)
+	},
+	".flac": func(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
+		return flac.Decode(f)
+	},
+	".ogg": decodeOggVorbis,
+	".oga": decodeOggVorbis,
+}
+
+func decodeAudioFile(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
+	ext := strings.ToLower(filepath.Ext(f.Name()))
+	decode, ok := audioDecoders[ext]
+	if !ok {
+		return nil, beep.Format{}, fmt.Errorf("unsupported audio format: %s", ext)
+	}
+	return decode(f)
+}
+
+type oggVorbisDecoder struct {
+	file     *os.File
+	reader   *oggvorbis.Reader
+	channels int
+	mix      [][2]float64
+	buf      []float32
+	err      error
+}
+
+func decodeOggVorbis(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
+	reader, err := oggvorbis.NewReader(f)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("ogg/vorbis: %v", err)
+	}
+
+	channels := reader.Channels()
+	if channels < 1 {
+		return nil, beep.Format{}, fmt.Errorf("ogg/vorbis: invalid channel count %d", channels)
+	}
+
+	format := beep.Format{
+		SampleRate:  beep.SampleRate(reader.SampleRate()),
+		NumChannels: 2,
+		Precision:   2,
+	}
+
+	return &oggVorbisDecoder{
+		file:     f,
+		reader:   reader,
+		channels: channels,
+		mix:      vorbisDownmix(channels),
+	}, format, nil
+}
+
+// vorbisDownmix returns the left and right gains of each channel, in the
+// Vorbis channel order, scaled so that neither side can clip. The LFE channel
+// is dropped.
+func vorbisDownmix(channels int) [][2]float64 {
+	const h = math.Sqrt2 / 2
+	var mix [][2]float64
+	switch channels {
+	case 1:
+		return [][2]float64{{1, 1}}
+	case 3:
+		mix = [][2]float64{{1, 0}, {h, h}, {0, 1}}
+	case 4:
+		mix = [][2]float64{{1, 0}, {0, 1}, {h, 0}, {0, h}}
+	case 5:
+		mix = [][2]float64{{1, 0}, {h, h}, {0, 1}, {h, 0}, {0, h}}
+	case 6:
+		mix = [][2]float64{{1, 0}, {h, h}, {0, 1}, {h, 0}, {0, h}, {0, 0}}
+	case 7:
+		mix = [][2]float64{{1, 0}, {h, h}, {0, 1}, {h, 0}, {0, h}, {h / 2, h / 2}, {0, 0}}
+	case 8:
+		mix = [][2]float64{{1, 0}, {h, h}, {0, 1}, {h, 0}, {0, h}, {h, 0}, {0, h}, {0, 0}}
+	default:
+		// Stereo, or an application-defined layout with no known order.
+		mix = make([][2]float64, channels)
+		mix[0], mix[1] = [2]float64{1, 0}, [2]float64{0, 1}
+		return mix
+	}
+
+	var total float64
+	for _, gains := range mix {
+		total += gains[0]
+	}
+	for i := range mix {
+		mix[i][0] /= total
+		mix[i][1] /= total
+	}
+	return mix
+}
+
+func (d *oggVorbisDecoder) Stream(samples [][2]float64) (n int, ok bool) {
+	if d.err != nil {
+		return 0, false
+	}
+
+	want := len(samples) * d.channels
+	if cap(d.buf) < want {
+		d.buf = make([]float32, want)
+	}
+
+	for n < len(samples) {
+		read, err := d.reader.Read(d.buf[:(len(samples)-n)*d.channels])
+		frames := read / d.channels
+
+		for i := 0; i < frames; i++ {
+			frame := d.buf[i*d.channels : (i+1)*d.channels]
+			var left, right float64
+			for ch, gains := range d.mix {
+				left += float64(frame[ch]) * gains[0]
+				right += float64(frame[ch]) * gains[1]
+			}
+			samples[n+i] = [2]float64{left, right}
+		}
+		n += frames
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			d.err = fmt.Errorf("ogg/vorbis: %v", err)
+			break
+		}
+		if read == 0 {
+			break
+		}
+	}
+
+	return n, n > 0
+}
+
+func (d *oggVorbisDecoder) Err() error {
+	return d.err
+}
+
+func (d *oggVorbisDecoder) Len() int {
+	return int(d.reader.Length())
+}
+
+func (d *oggVorbisDecoder) Position() int {
+	return int(d.reader.Position())
+}
+
+func (d *oggVorbisDecoder) Seek(p int) error {
+	if p < 0 || p > d.Len() {
+		return fmt.Errorf("ogg/vorbis: seek position %v out of range [%v, %v]", p, 0, d.Len())
+	}
+	if err := d.reader.SetPosition(int64(p)); err != nil {
+		return fmt.Errorf("ogg/vorbis: %v", err)
+	}
+	return nil
+}
+
+func (d *oggVorbisDecoder) Close() error {
+	return d.file.Close()
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"os"
+	"testing"
+)
+
+func TestOggVorbisLenAndSeek(t *testing.T) {
+	open := func() (*oggVorbisDecoder, func()) {
+		t.Helper()
+		f, err := os.Open("testdata/test.ogg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamer, _, err := decodeAudioFile(f)
+		if err != nil {
+			f.Close()
+			t.Fatal(err)
+		}
+		return streamer.(*oggVorbisDecoder), func() { streamer.Close() }
+	}
+
+	decoder, closeDecoder := open()
+	length := decoder.Len()
+	if length <= 0 {
+		t.Fatalf("Len = %d, want a positive length", length)
+	}
+	if streamed := streamAll(decoder); streamed != length {
+		t.Fatalf("streamed %d samples, Len reported %d", streamed, length)
+	}
+	closeDecoder()
+
+	tests := []struct {
+		name    string
+		seek    int
+		wantErr bool
+	}{
+		{"start", 0, false},
+		{"middle", length / 2, false},
+		{"end", length, false},
+		{"before start", -1, true},
+		{"past end", length + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, closeDecoder := open()
+			defer closeDecoder()
+
+			err := decoder.Seek(tt.seek)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Seek(%d) succeeded, want an error", tt.seek)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pos := decoder.Position(); pos != tt.seek {
+				t.Fatalf("Position after Seek(%d) = %d", tt.seek, pos)
+			}
+			if rest := streamAll(decoder); rest != length-tt.seek {
+				t.Fatalf("streamed %d samples after Seek(%d), want %d", rest, tt.seek, length-tt.seek)
+			}
+		})
+	}
+}
+
+func streamAll(decoder *oggVorbisDecoder) int {
+	total := 0
+	buf := make([][2]float64, 512)
+	for {
+		n, ok := decoder.Stream(buf)
+		total += n
+		if !ok {
+			return total
+		}
+	}
+}
+
+func TestVorbisDownmix(t *testing.T) {
+	downmix := func(frame []float64) [2]float64 {
+		var out [2]float64
+		for ch, gains := range vorbisDownmix(len(frame)) {
+			out[0] += frame[ch] * gains[0]
+			out[1] += frame[ch] * gains[1]
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		frame []float64
+		check func(out [2]float64) bool
+	}{
+		{"mono", []float64{0.5}, func(out [2]float64) bool { return out == [2]float64{0.5, 0.5} }},
+		{"stereo", []float64{0.5, -0.5}, func(out [2]float64) bool { return out == [2]float64{0.5, -0.5} }},
+		{"3.0 centre is not right", []float64{0, 1, 0}, func(out [2]float64) bool { return out[0] > 0 && out[0] == out[1] }},
+		{"3.0 right", []float64{0, 0, 1}, func(out [2]float64) bool { return out[0] == 0 && out[1] > 0 }},
+		{"5.1 front right", []float64{0, 0, 1, 0, 0, 0}, func(out [2]float64) bool { return out[0] == 0 && out[1] > 0 }},
+		{"5.1 rear left", []float64{0, 0, 0, 1, 0, 0}, func(out [2]float64) bool { return out[0] > 0 && out[1] == 0 }},
+		{"5.1 full scale does not clip", []float64{1, 1, 1, 1, 1, 1}, func(out [2]float64) bool { return out[0] <= 1+1e-9 && out[1] <= 1+1e-9 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := downmix(tt.frame); !tt.check(out) {
+				t.Fatalf("downmix(%v) = %v", tt.frame, out)
+			}
+		})
+	}
+}
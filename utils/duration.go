@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	mpegSyncSearch = 64 * 1024
+	oggTailSize    = 64 * 1024
+)
+
+var (
+	// Indexed by MPEG-1 or later, then layer, then the header's bitrate index.
+	mpegBitrates = [2][3][15]int{
+		{
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+		{
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+	}
+
+	// Indexed by the header's version bits, where 1 is reserved.
+	mpegSampleRates = [4][3]int{
+		{11025, 12000, 8000},
+		{},
+		{22050, 24000, 16000},
+		{44100, 48000, 32000},
+	}
+
+	lameEncoders = []string{"LAME", "Lavc", "Lavf"}
+)
+
+// readDuration reads the length from the file's headers without decoding.
+func readDuration(f *os.File) (time.Duration, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(filepath.Ext(f.Name())) {
+	case ".flac":
+		return flacDuration(f)
+	case ".wav":
+		return wavDuration(f, info.Size())
+	case ".mp3":
+		return mp3Duration(f, info.Size())
+	case ".ogg", ".oga":
+		return oggDuration(f, info.Size())
+	}
+	return 0, fmt.Errorf("unsupported format %s", filepath.Ext(f.Name()))
+}
+
+func samplesDuration(samples int64, sampleRate int) time.Duration {
+	return time.Duration(float64(samples) / float64(sampleRate) * float64(time.Second))
+}
+
+func id3v2Size(r io.ReaderAt) int64 {
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return 0
+	}
+
+	size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 | int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size
+}
+
+func flacDuration(r io.ReaderAt) (time.Duration, error) {
+	header := make([]byte, 8+34)
+	if _, err := r.ReadAt(header, id3v2Size(r)); err != nil {
+		return 0, err
+	}
+	if string(header[:4]) != "fLaC" || header[4]&0x7f != 0 {
+		return 0, fmt.Errorf("missing FLAC STREAMINFO block")
+	}
+
+	info := header[8:]
+	sampleRate := int(info[10])<<12 | int(info[11])<<4 | int(info[12])>>4
+	samples := int64(info[13]&0x0f)<<32 | int64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 {
+		return 0, fmt.Errorf("invalid FLAC sample rate")
+	}
+	return samplesDuration(samples, sampleRate), nil
+}
+
+func wavDuration(r io.ReaderAt, size int64) (time.Duration, error) {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return 0, err
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:]) != "WAVE" {
+		return 0, fmt.Errorf("not a RIFF WAVE file")
+	}
+
+	var byteRate int64
+	chunk := make([]byte, 8)
+	for offset := int64(12); offset+8 <= size; {
+		if _, err := r.ReadAt(chunk, offset); err != nil {
+			return 0, err
+		}
+		length := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		switch string(chunk[:4]) {
+		case "fmt ":
+			format := make([]byte, 16)
+			if _, err := r.ReadAt(format, offset+8); err != nil {
+				return 0, err
+			}
+			byteRate = int64(binary.LittleEndian.Uint32(format[8:12]))
+		case "data":
+			if byteRate == 0 {
+				return 0, fmt.Errorf("WAVE data chunk before fmt chunk")
+			}
+			// Streamed recordings may leave the size unset or too large.
+			if offset+8+length > size {
+				length = size - offset - 8
+			}
+			return time.Duration(float64(length) / float64(byteRate) * float64(time.Second)), nil
+		}
+
+		offset += 8 + length + length%2
+	}
+	return 0, fmt.Errorf("no WAVE data chunk")
+}
+
+type mpegFrame struct {
+	mpeg1      bool
+	layer      int
+	bitrate    int
+	sampleRate int
+	padding    int
+	mono       bool
+}
+
+func parseMPEGFrame(header []byte) (mpegFrame, bool) {
+	if len(header) < 4 || header[0] != 0xff || header[1]&0xe0 != 0xe0 {
+		return mpegFrame{}, false
+	}
+
+	version := header[1] >> 3 & 3
+	layer := 4 - int(header[1]>>1&3)
+	bitrateIndex := header[2] >> 4
+	rateIndex := header[2] >> 2 & 3
+	if version == 1 || layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mpegFrame{}, false
+	}
+
+	frame := mpegFrame{
+		mpeg1:      version == 3,
+		layer:      layer,
+		sampleRate: mpegSampleRates[version][rateIndex],
+		padding:    int(header[2] >> 1 & 1),
+		mono:       header[3]>>6 == 3,
+	}
+
+	row := 1
+	if frame.mpeg1 {
+		row = 0
+	}
+	frame.bitrate = mpegBitrates[row][layer-1][bitrateIndex] * 1000
+	return frame, true
+}
+
+func (f mpegFrame) samples() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && !f.mpeg1:
+		return 576
+	}
+	return 1152
+}
+
+func (f mpegFrame) length() int {
+	switch {
+	case f.layer == 1:
+		return (12*f.bitrate/f.sampleRate + f.padding) * 4
+	case f.layer == 3 && !f.mpeg1:
+		return 72*f.bitrate/f.sampleRate + f.padding
+	}
+	return 144*f.bitrate/f.sampleRate + f.padding
+}
+
+func (f mpegFrame) sideInfoSize() int {
+	switch {
+	case f.mpeg1 && f.mono:
+		return 17
+	case f.mpeg1:
+		return 32
+	case f.mono:
+		return 9
+	}
+	return 17
+}
+
+// Files without a Xing, Info or VBRI header are taken to be constant bitrate.
+func mp3Duration(r io.ReaderAt, size int64) (time.Duration, error) {
+	offset, frame, err := findMPEGFrame(r, id3v2Size(r), size)
+	if err != nil {
+		return 0, err
+	}
+
+	if samples, ok := mp3HeaderSamples(r, offset, frame); ok {
+		return samplesDuration(samples, frame.sampleRate), nil
+	}
+
+	audio := size - offset - mp3TagsSize(r, size)
+	seconds := float64(audio) * 8 / float64(frame.bitrate)
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func mp3TagsSize(r io.ReaderAt, size int64) int64 {
+	var tags int64
+	buf := make([]byte, 32)
+	if size >= 128 {
+		if n, _ := r.ReadAt(buf[:3], size-128); n == 3 && string(buf[:3]) == "TAG" {
+			tags = 128
+		}
+	}
+	if size-tags >= 32 {
+		if n, _ := r.ReadAt(buf, size-tags-32); n == 32 && string(buf[:8]) == "APETAGEX" {
+			tags += int64(binary.LittleEndian.Uint32(buf[12:]))
+			if binary.LittleEndian.Uint32(buf[20:])&(1<<31) != 0 {
+				tags += 32
+			}
+		}
+	}
+	return min(tags, size)
+}
+
+// A frame only counts when another header follows it, which skips stray sync
+// bytes in leftover tag data.
+func findMPEGFrame(r io.ReaderAt, offset, size int64) (int64, mpegFrame, error) {
+	if offset >= size {
+		return 0, mpegFrame{}, fmt.Errorf("no MPEG audio frames")
+	}
+
+	buf := make([]byte, min(mpegSyncSearch, size-offset))
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return 0, mpegFrame{}, err
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMPEGFrame(buf[i:])
+		if !ok {
+			continue
+		}
+		next := i + frame.length()
+		if next+4 <= len(buf) {
+			if _, ok := parseMPEGFrame(buf[next:]); !ok {
+				continue
+			}
+		}
+		return offset + int64(i), frame, nil
+	}
+	return 0, mpegFrame{}, fmt.Errorf("no MPEG audio frames")
+}
+
+func mp3HeaderSamples(r io.ReaderAt, offset int64, frame mpegFrame) (int64, bool) {
+	buf := make([]byte, frame.length())
+	if n, _ := r.ReadAt(buf, offset); n < len(buf) {
+		return 0, false
+	}
+
+	xing := 4 + frame.sideInfoSize()
+	if xing+12 <= len(buf) && (string(buf[xing:xing+4]) == "Xing" || string(buf[xing:xing+4]) == "Info") {
+		flags := binary.BigEndian.Uint32(buf[xing+4:])
+		if flags&1 == 0 {
+			return 0, false
+		}
+		frames := int64(binary.BigEndian.Uint32(buf[xing+8:]))
+		samples := frames * int64(frame.samples())
+
+		lame := xing + 8
+		for bit, size := range []int{4, 4, 100, 4} {
+			if flags&(1<<bit) != 0 {
+				lame += size
+			}
+		}
+		if lame+24 <= len(buf) && isLAMETag(buf[lame:lame+4]) {
+			delay := int64(buf[lame+21])<<4 | int64(buf[lame+22])>>4
+			padding := int64(buf[lame+22]&0x0f)<<8 | int64(buf[lame+23])
+			if samples > delay+padding {
+				samples -= delay + padding
+			}
+		}
+		return samples, true
+	}
+
+	const vbri = 4 + 32
+	if vbri+18 <= len(buf) && string(buf[vbri:vbri+4]) == "VBRI" {
+		frames := int64(binary.BigEndian.Uint32(buf[vbri+14:]))
+		return frames * int64(frame.samples()), true
+	}
+
+	return 0, false
+}
+
+func isLAMETag(b []byte) bool {
+	for _, encoder := range lameEncoders {
+		if string(b) == encoder {
+			return true
+		}
+	}
+	return false
+}
+
+func oggDuration(r io.ReaderAt, size int64) (time.Duration, error) {
+	first := make([]byte, 27+255+16)
+	if n, _ := r.ReadAt(first, 0); n < 28 || string(first[:4]) != "OggS" {
+		return 0, fmt.Errorf("not an Ogg file")
+	}
+	serial := binary.LittleEndian.Uint32(first[14:18])
+
+	packet := first[27+int(first[26]):]
+	if len(packet) < 16 || packet[0] != 1 || string(packet[1:7]) != "vorbis" {
+		return 0, fmt.Errorf("not an Ogg Vorbis stream")
+	}
+	sampleRate := int(binary.LittleEndian.Uint32(packet[12:16]))
+	if sampleRate == 0 {
+		return 0, fmt.Errorf("invalid Vorbis sample rate")
+	}
+
+	tailSize := min(oggTailSize, size)
+	tail := make([]byte, tailSize)
+	if _, err := r.ReadAt(tail, size-tailSize); err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	for i := bytes.LastIndex(tail, []byte("OggS")); i >= 0; i = bytes.LastIndex(tail[:i], []byte("OggS")) {
+		if i+27 > len(tail) || tail[i+4] != 0 || binary.LittleEndian.Uint32(tail[i+14:]) != serial {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(tail[i+6:]))
+		if granule >= 0 {
+			return samplesDuration(granule, sampleRate), nil
+		}
+	}
+	return 0, fmt.Errorf("no Ogg page with a granule position")
+}
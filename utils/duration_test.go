@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadDuration(t *testing.T) {
+	dir := t.TempDir()
+
+	wav := writeTestTrack(t, dir, "tone.wav", testTrackLength)
+	track, err := extractMetadata(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDuration(t, "wav", track.Duration, testTrackLength)
+
+	streamInfo := make([]byte, 34)
+	streamInfo[10], streamInfo[11], streamInfo[12] = 44100>>12, 44100>>4&0xff, 44100&0x0f<<4
+	binary.BigEndian.PutUint32(streamInfo[14:], 3*44100)
+	flac := append([]byte("fLaC\x00\x00\x00\x22"), streamInfo...)
+	assertFileDuration(t, filepath.Join(dir, "album.flac"), flac, 3*time.Second)
+
+	// 128 kbps MPEG-1 Layer III frames at 44.1 kHz are 417 bytes long.
+	frame := func() []byte {
+		f := make([]byte, 417)
+		copy(f, []byte{0xff, 0xfb, 0x90, 0x00})
+		return f
+	}
+
+	// Without a VBR header the length comes from the bitrate and the size of
+	// the audio between the tags.
+	var cbr []byte
+	cbr = append(cbr, "ID3\x03\x00\x00\x00\x00\x00\x10"...)
+	cbr = append(cbr, make([]byte, 16)...)
+	for i := 0; i < 100; i++ {
+		cbr = append(cbr, frame()...)
+	}
+	ape := make([]byte, 64)
+	copy(ape[32:], "APETAGEX")
+	binary.LittleEndian.PutUint32(ape[32+12:], 64)
+	cbr = append(cbr, ape...)
+	cbr = append(cbr, "TAG"...)
+	cbr = append(cbr, make([]byte, 125)...)
+	assertFileDuration(t, filepath.Join(dir, "cbr.mp3"), cbr, 100*417*8*time.Second/128000)
+
+	xing := frame()
+	copy(xing[36:], "Xing")
+	binary.BigEndian.PutUint32(xing[40:], 1)
+	binary.BigEndian.PutUint32(xing[44:], 1000)
+	copy(xing[48:], "LAME3.100")
+	// 576 samples of encoder delay and 1000 of padding.
+	xing[48+21], xing[48+22], xing[48+23] = 576>>4, 576&0x0f<<4|1000>>8, 1000&0xff
+	vbr := append(xing, frame()...)
+	assertFileDuration(t, filepath.Join(dir, "vbr.mp3"), vbr, samplesDuration(1000*1152-1576, 44100))
+
+	ident := []byte("\x01vorbis\x00\x00\x00\x00\x02")
+	ident = binary.LittleEndian.AppendUint32(ident, 48000)
+	ident = append(ident, make([]byte, 14)...)
+	ogg := append(oggPage(0, ident), oggPage(2*48000, make([]byte, 10))...)
+	ogg = append(ogg, oggPage(5*48000, make([]byte, 10))...)
+	assertFileDuration(t, filepath.Join(dir, "song.ogg"), ogg, 5*time.Second)
+}
+
+func oggPage(granule int64, packet []byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, 7)
+	page = append(page, make([]byte, 8)...)
+	page = append(page, 1, byte(len(packet)))
+	return append(page, packet...)
+}
+
+func assertFileDuration(t *testing.T, path string, data []byte, want time.Duration) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := readDuration(f)
+	if err != nil {
+		t.Fatalf("%s: %v", filepath.Base(path), err)
+	}
+	assertDuration(t, filepath.Base(path), got, want)
+}
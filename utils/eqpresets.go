@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type EqualizerPresetStore struct {
+	configDir string
+	presets   map[string]*EqualizerPreset
+}
+
+func NewEqualizerPresetStore() (*EqualizerPresetStore, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	es := &EqualizerPresetStore{
+		configDir: configDir,
+		presets:   make(map[string]*EqualizerPreset),
+	}
+
+	if err := os.MkdirAll(es.getPresetDir(), 0755); err != nil {
+		return nil, err
+	}
+
+	if err := es.loadPresets(); err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+func (es *EqualizerPresetStore) getPresetDir() string {
+	return filepath.Join(es.configDir, "equalizer")
+}
+
+func (es *EqualizerPresetStore) getPresetPath(name string) string {
+	return filepath.Join(es.getPresetDir(), name+".json")
+}
+
+func (es *EqualizerPresetStore) SavePreset(name string, gains [EqualizerBandCount]float64) error {
+	if name == "" {
+		return fmt.Errorf("preset name cannot be empty")
+	}
+
+	for _, preset := range builtinEqualizerPresets {
+		if preset.Name == name {
+			return fmt.Errorf("preset %s is built in", name)
+		}
+	}
+
+	preset := &EqualizerPreset{Name: name, Gains: gains}
+	data, err := json.MarshalIndent(preset, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(es.getPresetPath(name), data, 0644); err != nil {
+		return err
+	}
+
+	es.presets[name] = preset
+	return nil
+}
+
+func (es *EqualizerPresetStore) DeletePreset(name string) error {
+	if _, exists := es.presets[name]; !exists {
+		return fmt.Errorf("preset %s does not exist", name)
+	}
+
+	delete(es.presets, name)
+	return os.Remove(es.getPresetPath(name))
+}
+
+func (es *EqualizerPresetStore) ListPresets() []EqualizerPreset {
+	presets := BuiltinEqualizerPresets()
+
+	names := make([]string, 0, len(es.presets))
+	for name := range es.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		presets = append(presets, *es.presets[name])
+	}
+	return presets
+}
+
+func (es *EqualizerPresetStore) loadPresets() error {
+	dir := es.getPresetDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			var preset EqualizerPreset
+			if err := json.Unmarshal(data, &preset); err != nil {
+				continue
+			}
+
+			es.presets[preset.Name] = &preset
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+const (
+	EqualizerBandCount = 10
+	MaxEqualizerGain   = 12.0
+	equalizerQ         = 1.41
+)
+
+var EqualizerFrequencies = [EqualizerBandCount]float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+type EqualizerPreset struct {
+	Name  string
+	Gains [EqualizerBandCount]float64
+}
+
+var builtinEqualizerPresets = []EqualizerPreset{
+	{Name: "Flat"},
+	{Name: "Bass Boost", Gains: [EqualizerBandCount]float64{6, 5, 4, 2, 0, 0, 0, 0, 0, 0}},
+	{Name: "Treble Boost", Gains: [EqualizerBandCount]float64{0, 0, 0, 0, 0, 1, 2, 4, 5, 6}},
+	{Name: "Vocal", Gains: [EqualizerBandCount]float64{-2, -2, -1, 1, 3, 4, 3, 1, 0, -1}},
+	{Name: "Rock", Gains: [EqualizerBandCount]float64{4, 3, 2, 0, -1, -1, 1, 2, 3, 4}},
+	{Name: "Pop", Gains: [EqualizerBandCount]float64{-1, 1, 3, 4, 3, 0, -1, -1, 1, 2}},
+	{Name: "Classical", Gains: [EqualizerBandCount]float64{3, 2, 1, 0, 0, 0, 0, 1, 2, 3}},
+	{Name: "Electronic", Gains: [EqualizerBandCount]float64{5, 4, 1, 0, -2, 1, 0, 1, 4, 5}},
+}
+
+func BuiltinEqualizerPresets() []EqualizerPreset {
+	presets := make([]EqualizerPreset, len(builtinEqualizerPresets))
+	copy(presets, builtinEqualizerPresets)
+	return presets
+}
+
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	x1, x2     [2]float64
+	y1, y2     [2]float64
+}
+
+func (f *biquad) setPeaking(freq, gain float64, rate beep.SampleRate) {
+	if freq >= float64(rate)/2 || gain == 0 {
+		f.b0, f.b1, f.b2, f.a1, f.a2 = 1, 0, 0, 0, 0
+		return
+	}
+
+	a := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * freq / float64(rate)
+	alpha := math.Sin(w0) / (2 * equalizerQ)
+	cos := math.Cos(w0)
+	a0 := 1 + alpha/a
+
+	f.b0 = (1 + alpha*a) / a0
+	f.b1 = -2 * cos / a0
+	f.b2 = (1 - alpha*a) / a0
+	f.a1 = -2 * cos / a0
+	f.a2 = (1 - alpha/a) / a0
+}
+
+func (f *biquad) process(samples [][2]float64) {
+	for i := range samples {
+		for c := 0; c < 2; c++ {
+			x := samples[i][c]
+			y := f.b0*x + f.b1*f.x1[c] + f.b2*f.x2[c] - f.a1*f.y1[c] - f.a2*f.y2[c]
+			f.x2[c], f.x1[c] = f.x1[c], x
+			f.y2[c], f.y1[c] = f.y1[c], y
+			samples[i][c] = y
+		}
+	}
+}
+
+// equalizer fields are guarded by the output lock once it is playing.
+//
+// Boosting a band can push a full-scale signal past 0 dBFS, so the output is
+// attenuated by the largest boost. Cuts need no headroom.
+type equalizer struct {
+	streamer   beep.Streamer
+	sampleRate beep.SampleRate
+	gains      [EqualizerBandCount]float64
+	bands      [EqualizerBandCount]biquad
+	preamp     float64
+	active     bool
+}
+
+func newEqualizer(rate beep.SampleRate) *equalizer {
+	e := &equalizer{sampleRate: rate}
+	e.update()
+	return e
+}
+
+func (e *equalizer) setGain(band int, gain float64) {
+	if gain > MaxEqualizerGain {
+		gain = MaxEqualizerGain
+	}
+	if gain < -MaxEqualizerGain {
+		gain = -MaxEqualizerGain
+	}
+	e.gains[band] = gain
+	e.bands[band].setPeaking(EqualizerFrequencies[band], gain, e.sampleRate)
+	e.updateActive()
+}
+
+func (e *equalizer) setSampleRate(rate beep.SampleRate) {
+	e.sampleRate = rate
+	e.update()
+}
+
+func (e *equalizer) update() {
+	for i := range e.bands {
+		e.bands[i].setPeaking(EqualizerFrequencies[i], e.gains[i], e.sampleRate)
+	}
+	e.updateActive()
+}
+
+func (e *equalizer) updateActive() {
+	e.active = false
+	boost := 0.0
+	for _, gain := range e.gains {
+		if gain != 0 {
+			e.active = true
+		}
+		boost = math.Max(boost, gain)
+	}
+	e.preamp = math.Pow(10, -boost/20)
+}
+
+// preampDecibels returns the attenuation applied to make room for boosts.
+func (e *equalizer) preampDecibels() float64 {
+	return 20 * math.Log10(e.preamp)
+}
+
+func (e *equalizer) Stream(samples [][2]float64) (n int, ok bool) {
+	if e.streamer == nil {
+		return 0, false
+	}
+
+	n, ok = e.streamer.Stream(samples)
+	if e.active {
+		for i := range e.bands {
+			e.bands[i].process(samples[:n])
+		}
+		if e.preamp != 1 {
+			for i := range samples[:n] {
+				samples[i][0] *= e.preamp
+				samples[i][1] *= e.preamp
+			}
+		}
+	}
+	return n, ok
+}
+
+func (e *equalizer) Err() error {
+	if e.streamer == nil {
+		return nil
+	}
+	return e.streamer.Err()
+}
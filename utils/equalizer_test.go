@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/faiface/beep"
+)
+
+// sineLevel measures the steady-state amplitude of a sine at freq after f.
+func sineLevel(f *biquad, freq float64, rate beep.SampleRate) float64 {
+	samples := make([][2]float64, int(rate))
+	for i := range samples {
+		v := math.Sin(2 * math.Pi * freq * float64(i) / float64(rate))
+		samples[i] = [2]float64{v, v}
+	}
+	f.process(samples)
+
+	peak := 0.0
+	for _, s := range samples[len(samples)/2:] {
+		peak = math.Max(peak, math.Abs(s[0]))
+	}
+	return peak
+}
+
+func TestPeakingFilterGainAtCentre(t *testing.T) {
+	const rate = beep.SampleRate(44100)
+
+	for _, gain := range []float64{-12, -6, 3, 6, 12} {
+		for _, freq := range []float64{125, 1000, 8000} {
+			var f biquad
+			f.setPeaking(freq, gain, rate)
+
+			got := 20 * math.Log10(sineLevel(&f, freq, rate))
+			if math.Abs(got-gain) > 0.1 {
+				t.Errorf("%v Hz at %+v dB: measured %+.2f dB", freq, gain, got)
+			}
+		}
+	}
+
+	var flat biquad
+	flat.setPeaking(1000, 0, rate)
+	if got := sineLevel(&flat, 1000, rate); math.Abs(got-1) > 1e-3 {
+		t.Errorf("flat filter level = %v, want 1", got)
+	}
+}
+
+func TestEqualizerPreamp(t *testing.T) {
+	e := newEqualizer(44100)
+	if got := e.preampDecibels(); got != 0 {
+		t.Fatalf("flat preamp = %v dB, want 0", got)
+	}
+
+	e.setGain(2, 6)
+	e.setGain(5, MaxEqualizerGain+3)
+	e.setGain(8, -9)
+	if got := e.preampDecibels(); math.Abs(got+MaxEqualizerGain) > 1e-9 {
+		t.Fatalf("preamp = %v dB, want %v", got, -MaxEqualizerGain)
+	}
+
+	// A full-scale sine at the boosted band stays below clipping.
+	e.streamer = beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			v := math.Sin(2 * math.Pi * EqualizerFrequencies[5] * float64(i) / 44100)
+			samples[i] = [2]float64{v, v}
+		}
+		return len(samples), true
+	})
+	samples := make([][2]float64, 44100)
+	e.Stream(samples)
+	for i, s := range samples {
+		if math.Abs(s[0]) > 1.05 {
+			t.Fatalf("sample %d = %v clips", i, s[0])
+		}
+	}
+
+	e.setGain(2, -3)
+	e.setGain(5, -3)
+	if got := e.preampDecibels(); got != 0 {
+		t.Fatalf("preamp with only cuts = %v dB, want 0", got)
+	}
+}
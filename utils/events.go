@@ -0,0 +1,140 @@
+package utils
+
+import "time"
+
+type EventType int
+
+const (
+	EventTrackStarted EventType = iota
+	EventTrackEnded
+	EventPaused
+	EventResumed
+	EventSeeked
+	EventVolumeChanged
+	EventPlaybackError
+	EventQueueChanged
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventTrackStarted:
+		return "track started"
+	case EventTrackEnded:
+		return "track ended"
+	case EventPaused:
+		return "paused"
+	case EventResumed:
+		return "resumed"
+	case EventSeeked:
+		return "seeked"
+	case EventVolumeChanged:
+		return "volume changed"
+	case EventPlaybackError:
+		return "playback error"
+	case EventQueueChanged:
+		return "queue changed"
+	default:
+		return "unknown"
+	}
+}
+
+// EndReason says why a track ended.
+type EndReason int
+
+const (
+	// EndStopped is playback stopping without the listener moving on from
+	// the track: closing the player, the sleep timer, stop-after, restoring
+	// a session or replacing the context.
+	EndStopped EndReason = iota
+	EndSkipped
+	EndCompleted
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case EndSkipped:
+		return "skipped"
+	case EndCompleted:
+		return "completed"
+	default:
+		return "stopped"
+	}
+}
+
+type PlayerEvent struct {
+	Type      EventType
+	Time      time.Time
+	Track     Track
+	Index     int
+	Position  time.Duration
+	Duration  time.Duration
+	Completed bool
+	Reason    EndReason
+	Volume    float64
+	Err       error
+}
+
+const eventBufferSize = 64
+
+func (p *Player) Subscribe() (<-chan PlayerEvent, func()) {
+	ch := make(chan PlayerEvent, eventBufferSize)
+
+	p.subMu.Lock()
+	if p.subscribers == nil {
+		p.subscribers = make(map[chan PlayerEvent]struct{})
+	}
+	p.subscribers[ch] = struct{}{}
+	p.subMu.Unlock()
+
+	unsubscribe := func() {
+		p.subMu.Lock()
+		defer p.subMu.Unlock()
+		if _, ok := p.subscribers[ch]; ok {
+			delete(p.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (p *Player) emit(event PlayerEvent) {
+	event.Time = time.Now()
+
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for ch := range p.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (p *Player) emitTrackEnded(source *trackSource, reason EndReason) {
+	completed := reason == EndCompleted
+	position := source.totalTime()
+	if !completed {
+		position = source.currentTime()
+	}
+
+	p.emit(PlayerEvent{
+		Type:      EventTrackEnded,
+		Track:     source.track,
+		Index:     source.index,
+		Position:  position,
+		Duration:  source.totalTime(),
+		Completed: completed,
+		Reason:    reason,
+	})
+}
+
+func (p *Player) emitTrackStarted(source *trackSource) {
+	p.emit(PlayerEvent{
+		Type:     EventTrackStarted,
+		Track:    source.track,
+		Index:    source.index,
+		Duration: source.totalTime(),
+	})
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// collectEvents drains events until none arrive for a short while.
+func collectEvents(events <-chan PlayerEvent) []PlayerEvent {
+	var collected []PlayerEvent
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return collected
+			}
+			collected = append(collected, event)
+		case <-time.After(100 * time.Millisecond):
+			return collected
+		}
+	}
+}
+
+func TestEventDelivery(t *testing.T) {
+	player, _, first := newTestPlayer(t, 2)
+	second, unsubscribe := player.Subscribe()
+	defer unsubscribe()
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	player.Pause()
+	player.Resume()
+	if err := player.SeekTo(200 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	player.SetVolume(0.5)
+	if err := player.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []EventType{
+		EventTrackStarted,
+		EventPaused,
+		EventResumed,
+		EventSeeked,
+		EventVolumeChanged,
+		EventTrackEnded,
+		EventTrackStarted,
+	}
+
+	for name, events := range map[string]<-chan PlayerEvent{"first": first, "second": second} {
+		got := collectEvents(events)
+		if len(got) != len(want) {
+			t.Fatalf("%s subscriber got %d events %v, want %v", name, len(got), got, want)
+		}
+		for i, event := range got {
+			if event.Type != want[i] {
+				t.Fatalf("%s subscriber event %d = %s, want %s", name, i, event.Type, want[i])
+			}
+			if event.Time.IsZero() {
+				t.Fatalf("%s subscriber event %d has no time", name, i)
+			}
+		}
+
+		if seeked := got[3]; seeked.Index != 0 {
+			t.Errorf("%s: seeked index = %d, want 0", name, seeked.Index)
+		} else {
+			assertDuration(t, name+" seeked position", seeked.Position, 200*time.Millisecond)
+		}
+		if volume := got[4].Volume; volume != 0.5 {
+			t.Errorf("%s: volume = %v, want 0.5", name, volume)
+		}
+		if ended := got[5]; ended.Index != 0 || ended.Completed {
+			t.Errorf("%s: ended index %d completed %v, want index 0 not completed", name, ended.Index, ended.Completed)
+		}
+		if started := got[6]; started.Index != 1 {
+			t.Errorf("%s: started index = %d, want 1", name, started.Index)
+		}
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	player, _, _ := newTestPlayer(t, 1)
+	events, unsubscribe := player.Subscribe()
+
+	unsubscribe()
+	unsubscribe()
+
+	player.SetVolume(0.5)
+	if event, ok := <-events; ok {
+		t.Fatalf("received %s after unsubscribing", event.Type)
+	}
+}
+
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	player, _, _ := newTestPlayer(t, 1)
+	slow, unsubscribe := player.Subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*eventBufferSize; i++ {
+			player.SetVolume(float64(i%10) / 10)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("player blocked on a subscriber that is not reading")
+	}
+
+	if got := len(collectEvents(slow)); got != eventBufferSize {
+		t.Fatalf("slow subscriber kept %d events, want %d", got, eventBufferSize)
+	}
+}
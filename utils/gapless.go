@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"math"
+	"os"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+)
+
+const resampleQuality = 4
+
+type trackSource struct {
+	track      Track
+	index      int
+	shuffled   []Track
+	queued     bool
+	dequeue    bool
+	file       *os.File
+	streamer   beep.StreamSeekCloser
+	stream     beep.Streamer
+	gain       *effects.Volume
+	format     beep.Format
+	outputRate beep.SampleRate
+
+	loopStart    int
+	loopEnd      int
+	hasLoopStart bool
+	looping      bool
+}
+
+func openTrackSource(track Track, index int, outputRate beep.SampleRate) (*trackSource, error) {
+	f, err := os.Open(track.AudioFile())
+	if err != nil {
+		return nil, err
+	}
+
+	streamer, format, err := decodeAudioFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if track.isRegion() {
+		region, err := newRegionStreamer(streamer, format, track)
+		if err != nil {
+			streamer.Close()
+			f.Close()
+			return nil, err
+		}
+		streamer = region
+	}
+
+	source := &trackSource{
+		track:      track,
+		index:      index,
+		file:       f,
+		streamer:   streamer,
+		format:     format,
+		outputRate: outputRate,
+	}
+
+	var stream beep.Streamer = &loopStreamer{source: source}
+	if format.SampleRate != outputRate {
+		stream = beep.Resample(resampleQuality, format.SampleRate, outputRate, stream)
+	}
+
+	source.gain = &effects.Volume{
+		Streamer: stream,
+		Base:     10,
+	}
+	source.stream = source.gain
+
+	return source, nil
+}
+
+func (s *trackSource) Close() {
+	if s.streamer != nil {
+		s.streamer.Close()
+		s.streamer = nil
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+func (s *trackSource) setGain(decibels float64) {
+	s.gain.Volume = decibels / 20
+}
+
+func (s *trackSource) samplesToDuration(n int) time.Duration {
+	return time.Duration(float64(n) / float64(s.format.SampleRate) * float64(time.Second))
+}
+
+func (s *trackSource) durationToSamples(d time.Duration) int {
+	return int(float64(d) / float64(time.Second) * float64(s.format.SampleRate))
+}
+
+func (s *trackSource) totalTime() time.Duration {
+	return s.samplesToDuration(s.streamer.Len())
+}
+
+func (s *trackSource) currentTime() time.Duration {
+	return s.samplesToDuration(s.streamer.Position())
+}
+
+func (s *trackSource) toOutputSamples(n int) int {
+	return int(float64(n) * float64(s.outputRate) / float64(s.format.SampleRate))
+}
+
+func (s *trackSource) outputLen() int {
+	return s.toOutputSamples(s.streamer.Len())
+}
+
+func (s *trackSource) remaining() int {
+	if s.looping {
+		return math.MaxInt
+	}
+	return s.toOutputSamples(s.streamer.Len() - s.streamer.Position())
+}
+
+type trackSwitch struct {
+	finished *trackSource
+	started  *trackSource
+}
+
+// gaplessStreamer fields are guarded by the output lock. Track switches are
+// queued in pending, and switched wakes the player up to handle them.
+type gaplessStreamer struct {
+	current  *trackSource
+	next     *trackSource
+	fade     int
+	mix      [][2]float64
+	pending  []trackSwitch
+	switched chan struct{}
+	done     chan struct{}
+}
+
+func newGaplessStreamer(current *trackSource) *gaplessStreamer {
+	return &gaplessStreamer{
+		current:  current,
+		switched: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+func (g *gaplessStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) && g.current != nil {
+		chunk := samples[n:]
+		fading := false
+
+		if g.next != nil && g.fade > 0 {
+			remaining := g.current.remaining()
+			if remaining <= g.fade {
+				fading = true
+			} else if len(chunk) > remaining-g.fade {
+				chunk = chunk[:remaining-g.fade]
+			}
+		}
+
+		var sn int
+		var sok bool
+		if fading {
+			sn, sok = g.streamCrossfade(chunk)
+		} else {
+			sn, sok = g.current.stream.Stream(chunk)
+		}
+
+		n += sn
+		if !sok || sn == 0 {
+			g.advance()
+		}
+	}
+	return n, n > 0
+}
+
+func (g *gaplessStreamer) streamCrossfade(samples [][2]float64) (int, bool) {
+	remaining := g.current.remaining()
+	if len(samples) > remaining {
+		samples = samples[:remaining]
+	}
+
+	sn, sok := g.current.stream.Stream(samples)
+
+	if cap(g.mix) < sn {
+		g.mix = make([][2]float64, sn)
+	}
+	mix := g.mix[:sn]
+	mn, _ := g.next.stream.Stream(mix)
+	for i := mn; i < sn; i++ {
+		mix[i] = [2]float64{}
+	}
+
+	for i := 0; i < sn; i++ {
+		t := float64(g.fade-remaining+i) / float64(g.fade)
+		if t < 0 {
+			t = 0
+		}
+		if t > 1 {
+			t = 1
+		}
+		samples[i][0] = samples[i][0]*(1-t) + mix[i][0]*t
+		samples[i][1] = samples[i][1]*(1-t) + mix[i][1]*t
+	}
+
+	return sn, sok
+}
+
+func (g *gaplessStreamer) rewindNext() {
+	if g.next != nil && g.next.streamer.Position() != 0 {
+		g.next.streamer.Seek(0)
+	}
+}
+
+func (g *gaplessStreamer) Err() error {
+	if g.current != nil {
+		return g.current.streamer.Err()
+	}
+	return nil
+}
+
+func (g *gaplessStreamer) advance() {
+	finished := g.current
+	g.current = g.next
+	g.next = nil
+	g.fade = 0
+
+	g.pending = append(g.pending, trackSwitch{finished: finished, started: g.current})
+	select {
+	case g.switched <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/faiface/beep"
+)
+
+// constantStreamer plays length samples of a single value.
+type constantStreamer struct {
+	value  float64
+	length int
+	pos    int
+}
+
+func (c *constantStreamer) Stream(samples [][2]float64) (int, bool) {
+	n := min(len(samples), c.length-c.pos)
+	for i := range samples[:n] {
+		samples[i] = [2]float64{c.value, c.value}
+	}
+	c.pos += n
+	return n, n > 0
+}
+
+func (c *constantStreamer) Err() error    { return nil }
+func (c *constantStreamer) Len() int      { return c.length }
+func (c *constantStreamer) Position() int { return c.pos }
+func (c *constantStreamer) Close() error  { return nil }
+
+func (c *constantStreamer) Seek(p int) error {
+	c.pos = p
+	return nil
+}
+
+func constantSource(value float64, length int) *trackSource {
+	streamer := &constantStreamer{value: value, length: length}
+	format := beep.Format{SampleRate: defaultOutputSampleRate, NumChannels: 2, Precision: 2}
+	return &trackSource{streamer: streamer, stream: streamer, format: format, outputRate: format.SampleRate}
+}
+
+func TestCrossfadeMix(t *testing.T) {
+	const currentLen, nextLen = 1000, 800
+
+	tests := []struct {
+		name string
+		fade int
+	}{
+		{"gapless", 0},
+		{"short fade", 100},
+		{"long fade", 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGaplessStreamer(constantSource(1, currentLen))
+			g.next = constantSource(0.5, nextLen)
+			g.fade = tt.fade
+
+			var out []float64
+			buf := make([][2]float64, 64)
+			for {
+				n, ok := g.Stream(buf)
+				for _, s := range buf[:n] {
+					out = append(out, s[0])
+				}
+				if !ok {
+					break
+				}
+			}
+
+			if want := currentLen + nextLen - tt.fade; len(out) != want {
+				t.Fatalf("streamed %d samples, want %d", len(out), want)
+			}
+
+			spliceStart := currentLen - tt.fade
+			for i, got := range out {
+				want := 1.0
+				switch {
+				case i >= currentLen:
+					want = 0.5
+				case i >= spliceStart:
+					progress := float64(i-spliceStart) / float64(tt.fade)
+					want = 1 - progress + 0.5*progress
+				}
+				if math.Abs(got-want) > 1e-9 {
+					t.Fatalf("sample %d = %v, want %v", i, got, want)
+				}
+			}
+
+			if len(g.pending) != 2 || g.pending[0].started == nil || g.pending[1].started != nil {
+				t.Fatalf("pending switches = %+v, want a switch to the next track and then the end", g.pending)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HistoryStarted  = "started"
+	HistoryFinished = "finished"
+	HistorySkipped  = "skipped"
+	HistoryStopped  = "stopped"
+)
+
+type HistoryEntry struct {
+	Event    string
+	Time     time.Time
+	Track    Track
+	Listened time.Duration
+	Duration time.Duration
+}
+
+type HistoryStore struct {
+	path    string
+	mu      sync.Mutex
+	entries []HistoryEntry
+	version int
+}
+
+func NewHistoryStore() (*HistoryStore, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, err
+	}
+
+	hs := &HistoryStore{path: filepath.Join(configDir, "history.jsonl")}
+	if err := hs.load(); err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
+
+func (hs *HistoryStore) load() error {
+	f, err := os.Open(hs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		hs.entries = append(hs.entries, entry)
+	}
+	return scanner.Err()
+}
+
+func (hs *HistoryStore) Append(entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	f, err := os.OpenFile(hs.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	hs.entries = append(hs.entries, entry)
+	hs.version++
+	return nil
+}
+
+func (hs *HistoryStore) Version() int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.version
+}
+
+// Plays returns every play that ended, most recent first.
+func (hs *HistoryStore) Plays() []HistoryEntry {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	plays := make([]HistoryEntry, 0, len(hs.entries)/2)
+	for i := len(hs.entries) - 1; i >= 0; i-- {
+		if hs.entries[i].Event != HistoryStarted {
+			plays = append(plays, hs.entries[i])
+		}
+	}
+	return plays
+}
+
+// PlaysBetween returns plays in [from, to), most recent first.
+func (hs *HistoryStore) PlaysBetween(from, to time.Time) []HistoryEntry {
+	var plays []HistoryEntry
+	for _, play := range hs.Plays() {
+		if !play.Time.Before(from) && play.Time.Before(to) {
+			plays = append(plays, play)
+		}
+	}
+	return plays
+}
+
+// PlayCountsBetween counts finished plays in [from, to) by StatsKey.
+func (hs *HistoryStore) PlayCountsBetween(from, to time.Time) map[string]int {
+	counts := make(map[string]int)
+	for _, play := range hs.PlaysBetween(from, to) {
+		if play.Event == HistoryFinished {
+			counts[StatsKey(play.Track)]++
+		}
+	}
+	return counts
+}
+
+// Watch records the player's track starts and ends until the returned function
+// is called.
+func (hs *HistoryStore) Watch(p *Player) func() {
+	events, unsubscribe := p.Subscribe()
+	go hs.record(events)
+	return unsubscribe
+}
+
+func (hs *HistoryStore) record(events <-chan PlayerEvent) {
+	var listened time.Duration
+	var playingSince time.Time
+
+	for event := range events {
+		switch event.Type {
+		case EventTrackStarted:
+			listened = 0
+			playingSince = event.Time
+			hs.Append(HistoryEntry{
+				Event:    HistoryStarted,
+				Time:     event.Time,
+				Track:    event.Track,
+				Duration: event.Duration,
+			})
+
+		case EventPaused:
+			if !playingSince.IsZero() {
+				listened += event.Time.Sub(playingSince)
+				playingSince = time.Time{}
+			}
+
+		case EventResumed:
+			playingSince = event.Time
+
+		case EventTrackEnded:
+			if !playingSince.IsZero() {
+				listened += event.Time.Sub(playingSince)
+				playingSince = time.Time{}
+			}
+			if listened == 0 && !event.Completed {
+				continue
+			}
+
+			entry := HistoryEntry{
+				Event:    HistoryStopped,
+				Time:     event.Time,
+				Track:    event.Track,
+				Listened: listened,
+				Duration: event.Duration,
+			}
+			switch event.Reason {
+			case EndCompleted:
+				entry.Event = HistoryFinished
+			case EndSkipped:
+				entry.Event = HistorySkipped
+			}
+			hs.Append(entry)
+			listened = 0
+		}
+	}
+}
+
+// ParseDateRange accepts "2006-01-02", "2006-01-02..2006-01-31", "today",
+// "yesterday" or "7d" for the last seven days including today. The returned
+// range is [from, to) in local time.
+func ParseDateRange(input string, now time.Time) (time.Time, time.Time, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch {
+	case input == "today":
+		return today, today.AddDate(0, 0, 1), nil
+
+	case input == "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+
+	case strings.HasSuffix(input, "d"):
+		days, err := strconv.Atoi(strings.TrimSuffix(input, "d"))
+		if err != nil || days <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date range %q", input)
+		}
+		return today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1), nil
+	}
+
+	parts := strings.SplitN(input, "..", 2)
+	from, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(parts[0]), now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", parts[0])
+	}
+
+	to := from
+	if len(parts) == 2 {
+		to, err = time.ParseInLocation("2006-01-02", strings.TrimSpace(parts[1]), now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", parts[1])
+		}
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range ends before it starts")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
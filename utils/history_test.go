@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordsPlays(t *testing.T) {
+	player, output, events := newTestPlayer(t, 3)
+
+	history := &HistoryStore{path: filepath.Join(t.TempDir(), "history.jsonl")}
+	stop := history.Watch(player)
+	defer stop()
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	output.Advance(testTrackLength + 100*time.Millisecond)
+	waitForEvent(t, events, EventTrackStarted)
+	waitForEvent(t, events, EventTrackStarted)
+
+	if err := player.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(history.Plays()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("recorded %d plays, want 2", len(history.Plays()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	plays := history.Plays()
+	if plays[0].Event != HistorySkipped || plays[0].Track.Title != "track1.wav" {
+		t.Fatalf("latest play = %s %s, want skipped track1.wav", plays[0].Event, plays[0].Track.Title)
+	}
+	if plays[1].Event != HistoryFinished || plays[1].Track.Title != "track0.wav" {
+		t.Fatalf("first play = %s %s, want finished track0.wav", plays[1].Event, plays[1].Track.Title)
+	}
+
+	reloaded := &HistoryStore{path: history.path}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reloaded.Plays()); got != 2 {
+		t.Fatalf("reloaded %d plays, want 2", got)
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.Local)
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		input    string
+		from, to time.Time
+	}{
+		{"today", day(17), day(18)},
+		{"yesterday", day(16), day(17)},
+		{"7d", day(11), day(18)},
+		{"2026-10-05", day(5), day(6)},
+		{"2026-10-01..2026-10-10", day(1), day(11)},
+	}
+
+	for _, tt := range tests {
+		from, to, err := ParseDateRange(tt.input, now)
+		if err != nil {
+			t.Errorf("ParseDateRange(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("ParseDateRange(%q) = %v, %v; want %v, %v", tt.input, from, to, tt.from, tt.to)
+		}
+	}
+
+	for _, input := range []string{"", "0d", "last week", "2026-10-10..2026-10-01"} {
+		if _, _, err := ParseDateRange(input, now); err == nil {
+			t.Errorf("ParseDateRange(%q) expected an error", input)
+		}
+	}
+}
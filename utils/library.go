@@ -0,0 +1,471 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Missing lists the FILE entries a CUE sheet could not find. Failed files are
+// read again on the next scan.
+type IndexedFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Tracks  []Track
+	Missing []string
+	Failed  bool
+}
+
+// Bump libraryIndexVersion when scanning starts reading something new.
+const libraryIndexVersion = 2
+
+type LibraryIndex struct {
+	Version int
+	Root    string
+	Files   []IndexedFile
+}
+
+func getLibraryIndexPath(root string) (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(filepath.Clean(root)))
+	return filepath.Join(configDir, "library", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func LoadLibraryIndex(root string) (*LibraryIndex, error) {
+	path, err := getLibraryIndexPath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var index LibraryIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	if index.Version != libraryIndexVersion {
+		return nil, nil
+	}
+	return &index, nil
+}
+
+func (li *LibraryIndex) Save() error {
+	path, err := getLibraryIndexPath(li.Root)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(li)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (li *LibraryIndex) Tracks() []Track {
+	if li == nil {
+		return nil
+	}
+
+	cueTracks := make(map[string][]Track)
+	for _, file := range li.Files {
+		if isCueSheet(file.Path) {
+			for _, track := range file.Tracks {
+				cueTracks[track.File] = append(cueTracks[track.File], track)
+			}
+		}
+	}
+
+	var tracks []Track
+	for _, file := range li.Files {
+		if isCueSheet(file.Path) {
+			continue
+		}
+		if sheet, ok := cueTracks[file.Path]; ok {
+			tracks = append(tracks, sheet...)
+			continue
+		}
+		tracks = append(tracks, file.Tracks...)
+	}
+	return tracks
+}
+
+func isCueSheet(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".cue")
+}
+
+func (f IndexedFile) unchanged(info fs.FileInfo) bool {
+	return f.Size == info.Size() && f.ModTime.Equal(info.ModTime())
+}
+
+const scanProgressInterval = 100 * time.Millisecond
+
+type ScanOptions struct {
+	Workers int
+
+	// Progress is closed when ScanLibrary returns.
+	Progress chan<- ScanProgress
+}
+
+func (o ScanOptions) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return max(4, runtime.NumCPU())
+}
+
+type ScanProgress struct {
+	Dirs    int
+	Found   int
+	Queued  int
+	Parsed  int
+	Errors  int
+	Current string
+	Walked  bool
+	Elapsed time.Duration
+
+	// Tracks read since the previous update, in library order.
+	Tracks []Track
+}
+
+func (p ScanProgress) ETA() (time.Duration, bool) {
+	if !p.Walked || p.Parsed == 0 {
+		return 0, false
+	}
+	perFile := p.Elapsed / time.Duration(p.Parsed)
+	return perFile * time.Duration(p.Queued-p.Parsed), true
+}
+
+type scanTracker struct {
+	mu       sync.Mutex
+	progress ScanProgress
+	started  time.Time
+}
+
+func (t *scanTracker) update(f func(*ScanProgress)) {
+	t.mu.Lock()
+	f(&t.progress)
+	t.mu.Unlock()
+}
+
+func (t *scanTracker) snapshot() ScanProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress := t.progress
+	progress.Elapsed = time.Since(t.started)
+	t.progress.Tracks = nil
+	return progress
+}
+
+type scanJob struct {
+	index    int
+	seq      int
+	path     string
+	holdBack bool
+}
+
+type scanResult struct {
+	scanJob
+	tracks  []Track
+	missing []string
+	err     error
+}
+
+// ScanLibrary keeps the entries of files unchanged since previous.
+func ScanLibrary(ctx context.Context, root string, previous *LibraryIndex, opts ScanOptions) (*LibraryIndex, bool, error) {
+	known := make(map[string]IndexedFile)
+	if previous != nil {
+		for _, file := range previous.Files {
+			known[file.Path] = file
+		}
+	}
+
+	index := &LibraryIndex{Version: libraryIndexVersion, Root: root}
+	changed := previous == nil
+	tracker := &scanTracker{started: time.Now()}
+
+	jobs := make(chan scanJob)
+	results := make(chan scanResult)
+
+	var workers sync.WaitGroup
+	for i := 0; i < opts.workers(); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				result := scanResult{scanJob: job}
+				if isCueSheet(job.path) {
+					result.tracks, result.missing, result.err = parseCueSheet(job.path)
+				} else if track, err := extractMetadata(job.path); err != nil {
+					result.err = err
+				} else {
+					result.tracks = []Track{track}
+				}
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var walkErr error
+	go func() {
+		defer func() {
+			close(jobs)
+			workers.Wait()
+			close(results)
+		}()
+
+		queued := 0
+		send := func(job scanJob) bool {
+			job.seq = queued
+			queued++
+			tracker.update(func(p *ScanProgress) {
+				p.Queued++
+				p.Current = job.path
+			})
+
+			select {
+			case jobs <- job:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		modified := make(map[string]bool)
+		sheetDirs := make(map[string]bool)
+		walkErr = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				tracker.update(func(p *ScanProgress) { p.Errors++ })
+				return nil
+			}
+
+			if d.IsDir() {
+				tracker.update(func(p *ScanProgress) {
+					p.Dirs++
+					p.Current = path
+				})
+				if opts.Progress != nil {
+					sheetDirs[path] = hasCueSheet(path)
+				}
+				return nil
+			}
+
+			if !isAudioFile(path) && !isCueSheet(path) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				tracker.update(func(p *ScanProgress) { p.Errors++ })
+				return nil
+			}
+			tracker.update(func(p *ScanProgress) { p.Found++ })
+
+			file, ok := known[path]
+			if !ok || file.Failed || !file.unchanged(info) {
+				// A retry only counts as a change if it succeeds.
+				if !ok || !file.unchanged(info) {
+					changed = true
+				}
+				file = IndexedFile{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+				modified[path] = true
+			}
+			index.Files = append(index.Files, file)
+
+			if modified[path] && !isCueSheet(path) {
+				job := scanJob{index: len(index.Files) - 1, path: path, holdBack: sheetDirs[filepath.Dir(path)]}
+				if !send(job) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Sheets wait until the walk knows which of their audio files changed.
+		present := make(map[string]bool, len(index.Files))
+		for _, file := range index.Files {
+			present[file.Path] = true
+		}
+		modifiedStems := make(map[string]bool, len(modified))
+		for path := range modified {
+			modifiedStems[strings.TrimSuffix(path, filepath.Ext(path))] = true
+		}
+
+		for i, file := range index.Files {
+			if !isCueSheet(file.Path) {
+				continue
+			}
+			if !modified[file.Path] && !cueSourcesModified(root, file, present, modified, modifiedStems) {
+				continue
+			}
+			changed = true
+			if !send(scanJob{index: i, path: file.Path}) {
+				return
+			}
+		}
+
+		tracker.update(func(p *ScanProgress) { p.Walked = true })
+	}()
+
+	var ticks <-chan time.Time
+	if opts.Progress != nil {
+		defer close(opts.Progress)
+		ticker := time.NewTicker(scanProgressInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	report := func() {
+		select {
+		case opts.Progress <- tracker.snapshot():
+		case <-ctx.Done():
+		}
+	}
+
+	// Put results back in queue order before reporting their tracks.
+	pending := make(map[int]scanResult)
+	ready := make(map[int]scanResult)
+	var held []Track
+	next := 0
+	for collecting := true; collecting; {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				collecting = false
+				break
+			}
+			pending[result.index] = result
+			ready[result.seq] = result
+
+			var tracks []Track
+			for r, ok := ready[next]; ok; r, ok = ready[next] {
+				delete(ready, next)
+				next++
+				if r.holdBack {
+					held = append(held, r.tracks...)
+				} else {
+					tracks = append(tracks, r.tracks...)
+				}
+			}
+
+			tracker.update(func(p *ScanProgress) {
+				p.Parsed++
+				if result.err != nil {
+					p.Errors++
+				}
+				p.Tracks = append(p.Tracks, tracks...)
+			})
+		case <-ticks:
+			report()
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if opts.Progress != nil {
+		replaced := make(map[string]bool)
+		for _, result := range pending {
+			if isCueSheet(result.path) {
+				for _, track := range result.tracks {
+					replaced[track.File] = true
+				}
+			}
+		}
+		tracker.update(func(p *ScanProgress) {
+			for _, track := range held {
+				if !replaced[track.Path] {
+					p.Tracks = append(p.Tracks, track)
+				}
+			}
+		})
+		report()
+	}
+
+	for i, result := range pending {
+		index.Files[i].Tracks = result.tracks
+		index.Files[i].Missing = result.missing
+		index.Files[i].Failed = result.err != nil && !isCueSheet(index.Files[i].Path)
+		if known[index.Files[i].Path].Failed && !index.Files[i].Failed {
+			changed = true
+		}
+	}
+
+	if len(index.Files) != len(known) {
+		changed = true
+	}
+
+	return index, changed, walkErr
+}
+
+// Files outside root are not walked, so only the sheet's own changes count
+// for them.
+func cueSourcesModified(root string, sheet IndexedFile, present, modified, modifiedStems map[string]bool) bool {
+	for _, track := range sheet.Tracks {
+		if !withinDir(root, track.File) {
+			continue
+		}
+		if !present[track.File] || modified[track.File] {
+			return true
+		}
+	}
+	for _, stem := range sheet.Missing {
+		if modifiedStems[stem] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCueSheet(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && isCueSheet(entry.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
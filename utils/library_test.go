@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScanLibraryOrderAndCancel(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		sub := filepath.Join(dir, name)
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			want = append(want, writeTestTrack(t, sub, fmt.Sprintf("%d.wav", i), 50*time.Millisecond))
+		}
+	}
+
+	index, _, err := ScanLibrary(context.Background(), dir, nil, ScanOptions{Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks := index.Tracks()
+	if len(tracks) != len(want) {
+		t.Fatalf("scanned %d tracks, want %d", len(tracks), len(want))
+	}
+	for i, track := range tracks {
+		if track.Path != want[i] {
+			t.Fatalf("track %d = %s, want %s", i, track.Path, want[i])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if index, _, err := ScanLibrary(ctx, dir, nil, ScanOptions{}); err != context.Canceled || index != nil {
+		t.Fatalf("cancelled scan returned %v, %v; want no index and context.Canceled", index, err)
+	}
+}
+
+func TestScanLibraryIncremental(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	kept := writeTestTrack(t, dir, "kept.wav", testTrackLength)
+	edited := writeTestTrack(t, dir, "edited.wav", testTrackLength)
+	deleted := writeTestTrack(t, dir, "deleted.wav", testTrackLength)
+
+	index, changed, err := ScanLibrary(context.Background(), dir, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(index.Tracks()) != 3 {
+		t.Fatalf("first scan: changed %v, %d tracks; want changed with 3 tracks", changed, len(index.Tracks()))
+	}
+	if err := index.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := LoadLibraryIndex(dir)
+	if err != nil || saved == nil {
+		t.Fatalf("loading the saved index: %v", err)
+	}
+	for i := range saved.Files {
+		saved.Files[i].Tracks[0].Title = "cached"
+	}
+
+	if _, changed, _ := ScanLibrary(context.Background(), dir, saved, ScanOptions{Workers: 2}); changed {
+		t.Fatal("rescan of an untouched library reported changes")
+	}
+
+	writeTestTrack(t, dir, "edited.wav", 2*testTrackLength)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(edited, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+	added := writeTestTrack(t, dir, "added.wav", testTrackLength)
+
+	index, changed, err = ScanLibrary(context.Background(), dir, saved, ScanOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("rescan did not report changes")
+	}
+
+	titles := make(map[string]string)
+	for _, track := range index.Tracks() {
+		titles[track.Path] = track.Title
+	}
+	if len(titles) != 3 {
+		t.Fatalf("rescan has %d tracks, want 3", len(titles))
+	}
+	if titles[kept] != "cached" {
+		t.Error("unchanged file was read again")
+	}
+	if titles[edited] == "cached" {
+		t.Error("modified file was not read again")
+	}
+	if _, ok := titles[added]; !ok {
+		t.Error("new file is missing")
+	}
+	if _, ok := titles[deleted]; ok {
+		t.Error("deleted file is still listed")
+	}
+}
+
+func TestScanLibraryRetriesMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "later.wav")
+	link := filepath.Join(dir, "link.wav")
+	if err := os.Symlink(source, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "album.cue"), []byte(testCueSheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	index, _, err := ScanLibrary(context.Background(), dir, nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracks := index.Tracks(); len(tracks) != 0 {
+		t.Fatalf("first scan has %d tracks, want none: the link is dangling and the sheet has no audio", len(tracks))
+	}
+
+	// Nothing on disk changed, so the sheet is not read again, but the file
+	// that could not be opened is.
+	index, changed, err := ScanLibrary(context.Background(), dir, index, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Fatal("rescan re-read the sheet with a missing FILE")
+	}
+
+	writeTestTrack(t, filepath.Dir(source), "later.wav", testTrackLength)
+	writeTestTrack(t, dir, "album.wav", 1500*time.Millisecond)
+
+	index, changed, err = ScanLibrary(context.Background(), dir, index, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks := index.Tracks()
+	if !changed || len(tracks) != 4 {
+		t.Fatalf("rescan: changed %v, %d tracks; want the link and 3 sheet tracks", changed, len(tracks))
+	}
+	for _, track := range tracks {
+		if track.Path == filepath.Join(dir, "album.wav") {
+			t.Fatal("album.wav is listed as well as the sheet's tracks")
+		}
+	}
+}
+
+func TestScanLibraryProgress(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		writeTestTrack(t, dir, fmt.Sprintf("%d.wav", i), 50*time.Millisecond)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.mp3"), []byte("not audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	live := filepath.Join(dir, "live")
+	if err := os.Mkdir(live, 0755); err != nil {
+		t.Fatal(err)
+	}
+	album := writeTestTrack(t, live, "album.wav", 1500*time.Millisecond)
+	writeTestTrack(t, live, "bonus.wav", 50*time.Millisecond)
+	if err := os.WriteFile(filepath.Join(live, "album.cue"), []byte(testCueSheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := make(chan ScanProgress)
+	type scanned struct {
+		index *LibraryIndex
+		err   error
+	}
+	done := make(chan scanned, 1)
+	go func() {
+		index, _, err := ScanLibrary(context.Background(), dir, nil, ScanOptions{Workers: 3, Progress: progress})
+		done <- scanned{index, err}
+	}()
+
+	var last ScanProgress
+	var streamed []string
+	for update := range progress {
+		for _, track := range update.Tracks {
+			streamed = append(streamed, track.Path)
+		}
+		last = update
+	}
+	result := <-done
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	if !last.Walked || last.Dirs != 2 || last.Found != 9 || last.Queued != 9 || last.Parsed != 9 {
+		t.Fatalf("final progress = %+v, want the walk done with 9 of 9 files read in 2 directories", last)
+	}
+	if eta, ok := last.ETA(); !ok || eta != 0 {
+		t.Errorf("final ETA = %v, %v; want 0", eta, ok)
+	}
+
+	// Batches come in library order and never hold a file a sheet replaces.
+	var want []string
+	for _, track := range result.index.Tracks() {
+		want = append(want, track.Path)
+	}
+	if fmt.Sprint(streamed) != fmt.Sprint(want) {
+		t.Errorf("streamed tracks\n%v\nwant\n%v", streamed, want)
+	}
+	if slices.Contains(streamed, album) {
+		t.Errorf("%s was streamed although the sheet replaces it", album)
+	}
+}
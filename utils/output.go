@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+)
+
+// outputBuffer is how much audio the sound card is handed at a time.
+const outputBuffer = time.Second / 10
+
+type AudioOutput interface {
+	Init(rate beep.SampleRate, bufferSize int) error
+	Play(streamer beep.Streamer)
+	Clear()
+	Lock()
+	Unlock()
+	Close() error
+	// Latency is how long audio takes from being streamed to being heard.
+	Latency() time.Duration
+}
+
+type SpeakerOutput struct{}
+
+func (SpeakerOutput) Init(rate beep.SampleRate, bufferSize int) error {
+	return speaker.Init(rate, bufferSize)
+}
+
+func (SpeakerOutput) Play(streamer beep.Streamer) { speaker.Play(streamer) }
+func (SpeakerOutput) Clear()                      { speaker.Clear() }
+func (SpeakerOutput) Lock()                       { speaker.Lock() }
+func (SpeakerOutput) Unlock()                     { speaker.Unlock() }
+func (SpeakerOutput) Latency() time.Duration      { return outputBuffer }
+
+func (SpeakerOutput) Close() error {
+	speaker.Close()
+	return nil
+}
+
+// sinkOutput mixes streamers like the speaker does but hands every buffer to
+// write instead of a sound card. With a speed of zero nothing is pulled until
+// Advance is called, which keeps tests deterministic.
+type sinkOutput struct {
+	mu       sync.Mutex
+	mixer    beep.Mixer
+	rate     beep.SampleRate
+	buf      [][2]float64
+	speed    float64
+	consumed int
+	write    func(samples [][2]float64) error
+	err      error
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func (s *sinkOutput) Init(rate beep.SampleRate, bufferSize int) error {
+	s.halt()
+
+	s.mu.Lock()
+	s.rate = rate
+	s.buf = make([][2]float64, bufferSize)
+	s.mixer.Clear()
+	s.mu.Unlock()
+
+	if s.speed > 0 {
+		s.stop = make(chan struct{})
+		s.stopped = make(chan struct{})
+		go s.run(rate.D(bufferSize))
+	}
+	return nil
+}
+
+func (s *sinkOutput) run(period time.Duration) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(time.Duration(float64(period) / s.speed))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.pull(len(s.buf))
+		}
+	}
+}
+
+func (s *sinkOutput) halt() {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.stopped
+		s.stop, s.stopped = nil, nil
+	}
+}
+
+func (s *sinkOutput) pull(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buf == nil {
+		return
+	}
+
+	for n > 0 {
+		chunk := s.buf
+		if n < len(chunk) {
+			chunk = chunk[:n]
+		}
+		s.mixer.Stream(chunk)
+		s.consumed += len(chunk)
+		n -= len(chunk)
+
+		if s.write != nil && s.err == nil {
+			s.err = s.write(chunk)
+		}
+	}
+}
+
+// Advance pulls d worth of audio through the output immediately.
+func (s *sinkOutput) Advance(d time.Duration) {
+	s.mu.Lock()
+	rate := s.rate
+	s.mu.Unlock()
+
+	if rate > 0 {
+		s.pull(rate.N(d))
+	}
+}
+
+func (s *sinkOutput) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rate == 0 {
+		return 0
+	}
+	return s.rate.D(s.consumed)
+}
+
+func (s *sinkOutput) Play(streamer beep.Streamer) {
+	s.mu.Lock()
+	s.mixer.Add(streamer)
+	s.mu.Unlock()
+}
+
+func (s *sinkOutput) Clear() {
+	s.mu.Lock()
+	s.mixer.Clear()
+	s.mu.Unlock()
+}
+
+func (s *sinkOutput) Lock()                  { s.mu.Lock() }
+func (s *sinkOutput) Unlock()                { s.mu.Unlock() }
+func (s *sinkOutput) Latency() time.Duration { return 0 }
+
+type NullOutput struct {
+	sinkOutput
+}
+
+// NewNullOutput discards audio at speed times real time. A speed of zero only
+// consumes audio when Advance is called.
+func NewNullOutput(speed float64) *NullOutput {
+	return &NullOutput{sinkOutput{speed: speed}}
+}
+
+func (n *NullOutput) Close() error {
+	n.halt()
+	return nil
+}
+
+type WAVOutput struct {
+	sinkOutput
+	file    *os.File
+	written int
+}
+
+const wavHeaderSize = 44
+
+func NewWAVOutput(path string, speed float64) (*WAVOutput, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", path, err)
+	}
+
+	w := &WAVOutput{sinkOutput: sinkOutput{speed: speed}, file: file}
+	w.write = w.writeSamples
+
+	if _, err := file.Write(make([]byte, wavHeaderSize)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *WAVOutput) writeSamples(samples [][2]float64) error {
+	data := make([]byte, len(samples)*4)
+	for i, sample := range samples {
+		for c := 0; c < 2; c++ {
+			v := math.Max(-1, math.Min(1, sample[c]))
+			binary.LittleEndian.PutUint16(data[i*4+c*2:], uint16(int16(v*math.MaxInt16)))
+		}
+	}
+
+	n, err := w.file.Write(data)
+	w.written += n
+	return err
+}
+
+func (w *WAVOutput) Close() error {
+	w.halt()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return w.err
+	}
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+w.written))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 2)
+	binary.LittleEndian.PutUint32(header[24:], uint32(w.rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(w.rate)*4)
+	binary.LittleEndian.PutUint16(header[32:], 4)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(w.written))
+
+	_, err := w.file.Seek(0, io.SeekStart)
+	if err == nil {
+		_, err = w.file.Write(header)
+	}
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+
+	if err := w.file.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	w.file = nil
+	return w.err
+}
@@ -2,19 +2,13 @@ package utils
 
 import (
 	"fmt"
-	"math/rand"
-	"os"
-	"path/filepath"
+	"math"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
-	"github.com/faiface/beep/flac"
-	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
-	"github.com/faiface/beep/wav"
 )
 
 type RepeatMode int
@@ -39,169 +33,712 @@ func (r RepeatMode) String() string {
 }
 
 type Player struct {
-	tracks         []Track
-	shuffledTracks []Track
-	currentIndex   int
-	playing        bool
-	paused         bool
-	shuffle        bool
-	repeatMode     RepeatMode
-	streamer       beep.StreamSeekCloser
-	ctrl           *beep.Ctrl
-	format         beep.Format
-	currentTime    time.Duration
-	totalTime      time.Duration
-	speakerInit    bool
-	mu             sync.Mutex
-	currentFile    *os.File
-	volume         float64
-	volumeCtrl     *effects.Volume
-}
-
-func decodeAudioFile(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
-	ext := strings.ToLower(filepath.Ext(f.Name()))
-	switch ext {
-	case ".mp3":
-		return mp3.Decode(f)
-	case ".wav":
-		return wav.Decode(f)
-	case ".flac":
-		return flac.Decode(f)
-	default:
-		return nil, beep.Format{}, fmt.Errorf("unsupported audio format: %s", ext)
-	}
+	tracks            []Track
+	shuffledTracks    []Track
+	currentIndex      int
+	playing           bool
+	paused            bool
+	shuffleMode       ShuffleMode
+	shuffleSeed       int64
+	shuffleWeight     func(Track) float64
+	repeatMode        RepeatMode
+	source            *trackSource
+	gapless           *gaplessStreamer
+	ctrl              *beep.Ctrl
+	outputRate        beep.SampleRate
+	pendingOutputRate beep.SampleRate
+	currentTime       time.Duration
+	totalTime         time.Duration
+	outputInit        bool
+	output            AudioOutput
+	mu                sync.Mutex
+	volume            float64
+	volumeCtrl        *effects.Volume
+	fadeLevel         float64
+	equalizer         *equalizer
+	speedCtrl         *speedControl
+	samples           *sampleRing
+	crossfade         time.Duration
+	smartCrossfade    bool
+	replayGainMode    ReplayGainMode
+	preamp            float64
+	queue             []Track
+	queuedTrack       Track
+	playingQueued     bool
+	seekStep          time.Duration
+	largeSeekStep     time.Duration
+	stopAfter         StopAfterMode
+	sleepDeadline     time.Time
+	sleepFade         time.Duration
+	sleepDone         chan struct{}
+	clock             clock
+	nextGeneration    int
+	subMu             sync.Mutex
+	subscribers       map[chan PlayerEvent]struct{}
 }
 
+const (
+	defaultOutputSampleRate = beep.SampleRate(44100)
+	defaultSeekStep         = 5 * time.Second
+	defaultLargeSeekStep    = 60 * time.Second
+)
+
+var crossfadePresets = []time.Duration{
+	0,
+	2 * time.Second,
+	4 * time.Second,
+	6 * time.Second,
+	8 * time.Second,
+	12 * time.Second,
+}
+
+var preampPresets = []float64{0, 3, 6, -6, -3}
+
 func NewPlayer(tracks []Track) *Player {
+	return NewPlayerWithOutput(tracks, SpeakerOutput{})
+}
+
+func NewPlayerWithOutput(tracks []Track, output AudioOutput) *Player {
 	return &Player{
 		tracks:         tracks,
 		shuffledTracks: nil,
 		currentIndex:   0,
 		playing:        false,
-		shuffle:        false,
+		shuffleMode:    ShuffleOff,
 		repeatMode:     RepeatOff,
-		speakerInit:    false,
+		outputInit:     false,
+		output:         output,
+		outputRate:     defaultOutputSampleRate,
 		volume:         1.0,
+		fadeLevel:      1.0,
+		equalizer:      newEqualizer(defaultOutputSampleRate),
+		speedCtrl:      newSpeedControl(defaultOutputSampleRate),
+		samples:        &sampleRing{},
+		seekStep:       defaultSeekStep,
+		largeSeekStep:  defaultLargeSeekStep,
+		clock:          realClock{},
 	}
 }
 
 func (p *Player) createShuffledPlaylist() {
-	p.shuffledTracks = make([]Track, len(p.tracks))
-	copy(p.shuffledTracks, p.tracks)
-
-	rand.Seed(time.Now().UnixNano())
-	for i := len(p.shuffledTracks) - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
-		p.shuffledTracks[i], p.shuffledTracks[j] = p.shuffledTracks[j], p.shuffledTracks[i]
-	}
+	p.shuffledTracks = p.newShuffledPlaylist()
 }
 
 func (p *Player) getCurrentPlaylist() []Track {
-	if p.shuffle && p.shuffledTracks != nil {
+	if p.shuffling() && p.shuffledTracks != nil {
 		return p.shuffledTracks
 	}
 	return p.tracks
 }
 
+func (p *Player) currentTrack() (Track, bool) {
+	if p.playingQueued {
+		return p.queuedTrack, true
+	}
+
+	playlist := p.getCurrentPlaylist()
+	if p.currentIndex < len(playlist) {
+		return playlist[p.currentIndex], true
+	}
+	return Track{}, false
+}
+
 func (p *Player) Stop() {
+	p.stop(EndStopped)
+}
+
+func (p *Player) stop(reason EndReason) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if p.ctrl != nil {
-		speaker.Lock()
+		p.output.Lock()
 		p.ctrl.Paused = true
 		p.ctrl.Streamer = nil
-		speaker.Unlock()
+		p.output.Unlock()
 	}
 
-	speaker.Clear()
+	p.output.Clear()
 
-	if p.streamer != nil {
-		p.streamer.Close()
-		p.streamer = nil
-	}
-
-	if p.currentFile != nil {
-		p.currentFile.Close()
-		p.currentFile = nil
+	if p.source != nil {
+		p.emitTrackEnded(p.source, reason)
 	}
+	p.releaseSources()
 
 	p.playing = false
 	p.paused = false
 	p.currentTime = 0
 }
 
+func (p *Player) Close() error {
+	p.Stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.outputInit = false
+	return p.output.Close()
+}
+
 func (p *Player) Play() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.play(false)
+}
 
-	playlist := p.getCurrentPlaylist()
-	if p.currentIndex >= len(playlist) {
-		return nil
-	}
+func (p *Player) Cue(position time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	if p.streamer != nil {
-		p.streamer.Close()
-		p.streamer = nil
-	}
-	if p.currentFile != nil {
-		p.currentFile.Close()
-		p.currentFile = nil
+	if err := p.play(true); err != nil || p.source == nil {
+		return err
 	}
+	return p.seekTo(position)
+}
 
-	track := playlist[p.currentIndex]
-	f, err := os.Open(track.Path)
-	if err != nil {
-		return err
+func (p *Player) play(paused bool) error {
+	track, ok := p.currentTrack()
+	if !ok {
+		return nil
 	}
 
-	streamer, format, err := decodeAudioFile(f)
+	p.releaseSources()
+	p.applyPendingOutputRate()
+
+	source, err := openTrackSource(track, p.currentIndex, p.outputRate)
 	if err != nil {
-		f.Close()
+		p.emit(PlayerEvent{Type: EventPlaybackError, Track: track, Index: p.currentIndex, Err: err})
 		return err
 	}
+	source.queued = p.playingQueued
+
+	p.source = source
+	p.totalTime = source.totalTime()
+	p.applyReplayGain(source)
+
+	if !p.outputInit {
+		if err := p.output.Init(p.outputRate, p.outputRate.N(outputBuffer)); err != nil {
+			p.releaseSources()
+			err = fmt.Errorf("failed to initialize audio output: %v", err)
+			p.emit(PlayerEvent{Type: EventPlaybackError, Track: track, Index: p.currentIndex, Err: err})
+			return err
+		}
+		p.outputInit = true
+	}
 
-	p.currentFile = f
-	p.streamer = streamer
-	p.format = format
-	p.totalTime = time.Duration(float64(streamer.Len()) / float64(format.SampleRate) * float64(time.Second))
+	p.gapless = newGaplessStreamer(source)
 
-	if !p.speakerInit {
-		speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10))
-		p.speakerInit = true
-	}
+	p.output.Lock()
+	p.speedCtrl.setStreamer(p.gapless)
+	p.equalizer.streamer = p.speedCtrl
+	p.output.Unlock()
 
+	volume := p.volume * p.fadeLevel
 	p.volumeCtrl = &effects.Volume{
-		Streamer: p.streamer,
+		Streamer: p.equalizer,
 		Base:     2,
-		Volume:   p.volumeToDecibels(p.volume),
-		Silent:   p.volume < 0.0001,
+		Volume:   p.volumeToDecibels(volume),
+		Silent:   volume < 0.0001,
 	}
 
-	done := make(chan bool)
 	p.ctrl = &beep.Ctrl{
-		Streamer: beep.Seq(p.volumeCtrl, beep.Callback(func() {
-			done <- true
-		})),
-		Paused: false,
+		Streamer: p.volumeCtrl,
+		Paused:   paused,
 	}
 
-	speaker.Play(p.ctrl)
-	p.playing = true
-	p.paused = false
+	p.output.Play(&sampleTap{streamer: p.ctrl, ring: p.samples})
+	p.playing = !paused
+	p.paused = paused
+
+	go p.watchTrackSwitches(p.gapless)
+
+	p.emitTrackStarted(source)
+	if paused {
+		p.emit(PlayerEvent{Type: EventPaused, Track: track, Index: p.currentIndex})
+	}
+
+	p.prepareNext()
+	return nil
+}
+
+func (p *Player) releaseSources() {
+	if p.gapless != nil {
+		close(p.gapless.done)
+
+		p.output.Lock()
+		current, next := p.gapless.current, p.gapless.next
+		p.gapless.current, p.gapless.next = nil, nil
+		p.output.Unlock()
+
+		if next != nil {
+			next.Close()
+		}
+		if current != nil && current != p.source {
+			current.Close()
+		}
+		p.gapless = nil
+	}
+
+	if p.source != nil {
+		p.source.Close()
+		p.source = nil
+	}
+}
+
+func (p *Player) nextIndex() (int, []Track, bool) {
+	playlist := p.getCurrentPlaylist()
+	if len(playlist) == 0 {
+		return 0, nil, false
+	}
+
+	switch p.repeatMode {
+	case RepeatAll:
+		next := (p.currentIndex + 1) % len(playlist)
+		if p.shuffling() && p.currentIndex == len(playlist)-1 {
+			return 0, p.newShuffledPlaylist(), true
+		}
+		return next, nil, true
+	default:
+		if p.currentIndex < len(playlist)-1 {
+			return p.currentIndex + 1, nil, true
+		}
+		return 0, nil, false
+	}
+}
+
+type upcomingTrack struct {
+	track    Track
+	index    int
+	shuffled []Track
+	queued   bool
+	dequeue  bool
+}
+
+func (p *Player) peekNext() (upcomingTrack, bool) {
+	if p.repeatMode == RepeatOne {
+		track, ok := p.currentTrack()
+		return upcomingTrack{track: track, index: p.currentIndex, queued: p.playingQueued}, ok
+	}
+
+	if len(p.queue) > 0 {
+		return upcomingTrack{track: p.queue[0], index: p.currentIndex, queued: true, dequeue: true}, true
+	}
+
+	index, shuffled, ok := p.nextIndex()
+	if !ok {
+		return upcomingTrack{}, false
+	}
+
+	playlist := p.getCurrentPlaylist()
+	if shuffled != nil {
+		playlist = shuffled
+	}
+	return upcomingTrack{track: playlist[index], index: index, shuffled: shuffled}, true
+}
+
+// prepareNext releases p.mu while it opens the next file.
+func (p *Player) prepareNext() {
+	g := p.gapless
+	if g == nil {
+		return
+	}
+
+	p.nextGeneration++
+	generation := p.nextGeneration
+
+	p.output.Lock()
+	stale := g.next
+	g.next = nil
+	p.output.Unlock()
+
+	if stale != nil {
+		stale.Close()
+	}
+
+	// A new output rate waits for play to reopen the output.
+	upcoming, ok := p.peekNext()
+	if !ok || p.stopsAfter(upcoming.track) || p.pendingOutputRate != 0 {
+		return
+	}
+	outputRate := p.outputRate
+
+	p.mu.Unlock()
+	next, err := openTrackSource(upcoming.track, upcoming.index, outputRate)
+	p.mu.Lock()
+
+	if p.gapless != g || p.nextGeneration != generation {
+		if next != nil {
+			next.Close()
+		}
+		return
+	}
+	if err != nil {
+		p.emit(PlayerEvent{Type: EventPlaybackError, Track: upcoming.track, Index: upcoming.index, Err: err})
+		return
+	}
+	next.shuffled = upcoming.shuffled
+	next.queued = upcoming.queued
+	next.dequeue = upcoming.dequeue
+	p.applyReplayGain(next)
+
+	p.output.Lock()
+	g.next = next
+	g.fade = p.crossfadeSamples(next)
+	p.output.Unlock()
+}
+
+func (p *Player) crossfadeSamples(next *trackSource) int {
+	if p.crossfade <= 0 || p.source == nil || next == nil || p.repeatMode == RepeatOne {
+		return 0
+	}
+
+	current := p.source.track
+	if p.smartCrossfade && current.Album != "" &&
+		strings.EqualFold(current.Album, next.track.Album) &&
+		strings.EqualFold(current.Artist, next.track.Artist) {
+		return 0
+	}
+
+	fade := p.outputRate.N(p.crossfade)
+	if half := p.source.outputLen() / 2; fade > half {
+		fade = half
+	}
+	if fade > next.outputLen() {
+		fade = next.outputLen()
+	}
+	return fade
+}
+
+func (p *Player) updateCrossfade() {
+	if p.gapless == nil {
+		return
+	}
+
+	p.output.Lock()
+	if p.gapless.next != nil {
+		p.gapless.fade = p.crossfadeSamples(p.gapless.next)
+	}
+	p.output.Unlock()
+}
+
+// SetOutputSampleRate takes effect at the next track boundary.
+func (p *Player) SetOutputSampleRate(rate beep.SampleRate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if rate <= 0 {
+		return
+	}
+	if rate == p.outputRate {
+		p.pendingOutputRate = 0
+	} else {
+		p.pendingOutputRate = rate
+	}
+	p.prepareNext()
+}
+
+func (p *Player) GetOutputSampleRate() beep.SampleRate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pendingOutputRate != 0 {
+		return p.pendingOutputRate
+	}
+	return p.outputRate
+}
+
+func (p *Player) applyPendingOutputRate() {
+	if p.pendingOutputRate == 0 {
+		return
+	}
+
+	rate := p.pendingOutputRate
+	p.pendingOutputRate = 0
+	p.outputRate = rate
+	p.outputInit = false
+
+	p.output.Lock()
+	p.equalizer.setSampleRate(rate)
+	p.speedCtrl.setSampleRate(rate)
+	p.output.Unlock()
+}
+
+func (p *Player) applyReplayGain(source *trackSource) {
+	source.setGain(replayGainDecibels(source.track, p.replayGainMode, p.preamp))
+}
+
+func (p *Player) updateReplayGain() {
+	if p.gapless == nil {
+		return
+	}
+
+	p.output.Lock()
+	if p.gapless.current != nil {
+		p.applyReplayGain(p.gapless.current)
+	}
+	if p.gapless.next != nil {
+		p.applyReplayGain(p.gapless.next)
+	}
+	p.output.Unlock()
+}
+
+func (p *Player) SetReplayGainMode(mode ReplayGainMode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replayGainMode = mode
+	p.updateReplayGain()
+}
+
+func (p *Player) CycleReplayGainMode() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replayGainMode = (p.replayGainMode + 1) % 3
+	p.updateReplayGain()
+}
+
+func (p *Player) GetReplayGainMode() ReplayGainMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.replayGainMode
+}
+
+func (p *Player) SetPreamp(decibels float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.preamp = decibels
+	p.updateReplayGain()
+}
+
+func (p *Player) CyclePreamp() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := preampPresets[0]
+	for i, preset := range preampPresets {
+		if preset == p.preamp {
+			next = preampPresets[(i+1)%len(preampPresets)]
+			break
+		}
+	}
+	p.preamp = next
+	p.updateReplayGain()
+}
+
+func (p *Player) GetPreamp() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.preamp
+}
+
+func (p *Player) SetEqualizerGain(band int, gain float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if band < 0 || band >= EqualizerBandCount {
+		return fmt.Errorf("equalizer band out of range")
+	}
+
+	p.output.Lock()
+	p.equalizer.setGain(band, gain)
+	p.output.Unlock()
+	return nil
+}
+
+func (p *Player) SetEqualizerGains(gains [EqualizerBandCount]float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.output.Lock()
+	for band, gain := range gains {
+		p.equalizer.setGain(band, gain)
+	}
+	p.output.Unlock()
+}
+
+func (p *Player) GetEqualizerGains() [EqualizerBandCount]float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.equalizer.gains
+}
+
+func (p *Player) GetEqualizerPreamp() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.equalizer.preampDecibels()
+}
+
+func (p *Player) SetSpeed(speed float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if speed < MinPlaybackSpeed {
+		speed = MinPlaybackSpeed
+	}
+	if speed > MaxPlaybackSpeed {
+		speed = MaxPlaybackSpeed
+	}
+
+	p.output.Lock()
+	p.speedCtrl.setSpeed(math.Round(speed*100) / 100)
+	p.output.Unlock()
+}
+
+func (p *Player) GetSpeed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.output.Lock()
+	defer p.output.Unlock()
+	return p.speedCtrl.speed
+}
+
+func (p *Player) SetPreservePitch(preserve bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.output.Lock()
+	if p.speedCtrl.preservePitch != preserve {
+		p.speedCtrl.setPreservePitch(preserve)
+	}
+	p.output.Unlock()
+}
+
+func (p *Player) TogglePreservePitch() {
+	p.SetPreservePitch(!p.GetPreservePitch())
+}
+
+func (p *Player) GetPreservePitch() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.output.Lock()
+	defer p.output.Unlock()
+	return p.speedCtrl.preservePitch
+}
+
+func (p *Player) SetCrossfade(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if d < 0 {
+		d = 0
+	}
+	p.crossfade = d
+	p.updateCrossfade()
+}
+
+func (p *Player) CycleCrossfade() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := crossfadePresets[0]
+	for i, preset := range crossfadePresets {
+		if preset == p.crossfade {
+			next = crossfadePresets[(i+1)%len(crossfadePresets)]
+			break
+		}
+	}
+	p.crossfade = next
+	p.updateCrossfade()
+}
+
+func (p *Player) GetCrossfade() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.crossfade
+}
+
+func (p *Player) ToggleSmartCrossfade() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.smartCrossfade = !p.smartCrossfade
+	p.updateCrossfade()
+}
+
+func (p *Player) GetSmartCrossfade() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.smartCrossfade
+}
+
+func (p *Player) watchTrackSwitches(g *gaplessStreamer) {
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-g.switched:
+		}
 
-	go func() {
-		<-done
 		p.mu.Lock()
-		wasPlaying := p.playing
+		if !p.applyTrackSwitches(g) {
+			return
+		}
 		p.mu.Unlock()
+	}
+}
 
-		if wasPlaying {
-			p.HandleTrackEnd()
+// applyTrackSwitches returns false, with p.mu released, once g is no longer the
+// player's streamer.
+func (p *Player) applyTrackSwitches(g *gaplessStreamer) bool {
+	switched := false
+	for {
+		if p.gapless != g {
+			p.mu.Unlock()
+			return false
 		}
-	}()
 
-	return nil
+		p.output.Lock()
+		if len(g.pending) == 0 {
+			p.output.Unlock()
+			break
+		}
+		sw := g.pending[0]
+		g.pending = g.pending[1:]
+		p.output.Unlock()
+
+		if sw.finished != nil {
+			p.emitTrackEnded(sw.finished, EndCompleted)
+			sw.finished.Close()
+		}
+
+		if sw.started == nil {
+			p.source = nil
+			wasPlaying := p.playing
+			p.mu.Unlock()
+
+			if wasPlaying {
+				p.HandleTrackEnd()
+			}
+			return false
+		}
+
+		if sw.started.shuffled != nil && p.shuffling() {
+			p.shuffledTracks = sw.started.shuffled
+		}
+		if sw.started.dequeue && len(p.queue) > 0 {
+			p.queue = p.queue[1:]
+			p.emit(PlayerEvent{Type: EventQueueChanged})
+		}
+		p.playingQueued = sw.started.queued
+		if sw.started.queued {
+			p.queuedTrack = sw.started.track
+		}
+		p.currentIndex = sw.started.index
+		p.source = sw.started
+		p.totalTime = sw.started.totalTime()
+		p.currentTime = 0
+		p.emitTrackStarted(sw.started)
+		switched = true
+	}
+
+	if switched {
+		p.prepareNext()
+		if p.gapless != g {
+			p.mu.Unlock()
+			return false
+		}
+	}
+	return true
 }
 
 func (p *Player) Pause() {
@@ -209,11 +746,17 @@ func (p *Player) Pause() {
 	defer p.mu.Unlock()
 
 	if p.ctrl != nil && p.playing {
-		speaker.Lock()
+		p.output.Lock()
 		p.ctrl.Paused = true
-		speaker.Unlock()
+		if p.source != nil {
+			p.currentTime = p.source.currentTime()
+		}
+		p.output.Unlock()
 		p.paused = true
 		p.playing = false
+
+		track, _ := p.currentTrack()
+		p.emit(PlayerEvent{Type: EventPaused, Track: track, Index: p.currentIndex, Position: p.currentTime})
 	}
 }
 
@@ -222,22 +765,40 @@ func (p *Player) Resume() {
 	defer p.mu.Unlock()
 
 	if p.ctrl != nil && p.paused {
-		speaker.Lock()
+		p.output.Lock()
 		p.ctrl.Paused = false
-		speaker.Unlock()
+		p.output.Unlock()
 		p.playing = true
 		p.paused = false
+
+		track, _ := p.currentTrack()
+		p.emit(PlayerEvent{Type: EventResumed, Track: track, Index: p.currentIndex, Position: p.currentTime})
 	}
 }
 
 func (p *Player) Next() error {
-	p.Stop()
+	p.stop(EndSkipped)
 
 	p.mu.Lock()
+	if len(p.queue) > 0 {
+		p.queuedTrack = p.queue[0]
+		p.queue = p.queue[1:]
+		p.playingQueued = true
+		p.emit(PlayerEvent{Type: EventQueueChanged})
+		p.mu.Unlock()
+		return p.Play()
+	}
+
 	playlist := p.getCurrentPlaylist()
+	if len(playlist) == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	p.playingQueued = false
+	wrapped := p.currentIndex == len(playlist)-1
 	p.currentIndex = (p.currentIndex + 1) % len(playlist)
 
-	if p.shuffle && p.currentIndex == 0 && p.repeatMode == RepeatAll {
+	if p.shuffling() && wrapped && p.repeatMode == RepeatAll {
 		p.createShuffledPlaylist()
 	}
 	p.mu.Unlock()
@@ -246,11 +807,16 @@ func (p *Player) Next() error {
 }
 
 func (p *Player) Previous() error {
-	p.Stop()
+	p.stop(EndSkipped)
 
 	p.mu.Lock()
 	playlist := p.getCurrentPlaylist()
-	p.currentIndex = (p.currentIndex - 1 + len(playlist)) % len(playlist)
+	if p.playingQueued {
+		p.playingQueued = false
+		p.currentIndex = max(p.currentIndex, 0)
+	} else if len(playlist) > 0 {
+		p.currentIndex = (p.currentIndex - 1 + len(playlist)) % len(playlist)
+	}
 	p.mu.Unlock()
 
 	return p.Play()
@@ -261,10 +827,11 @@ func (p *Player) Skip(index int) error {
 		return fmt.Errorf("index out of range")
 	}
 
-	p.Stop()
+	p.stop(EndSkipped)
 
 	p.mu.Lock()
-	if p.shuffle && p.shuffledTracks != nil {
+	p.playingQueued = false
+	if p.shuffling() && p.shuffledTracks != nil {
 		targetTrack := p.tracks[index]
 		for i, track := range p.shuffledTracks {
 			if track.Path == targetTrack.Path {
@@ -280,48 +847,18 @@ func (p *Player) Skip(index int) error {
 	return p.Play()
 }
 
-func (p *Player) ToggleShuffle() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	p.shuffle = !p.shuffle
-
-	if p.shuffle {
-		p.createShuffledPlaylist()
-
-		if p.currentIndex < len(p.tracks) {
-			currentTrack := p.tracks[p.currentIndex]
-			for i, track := range p.shuffledTracks {
-				if track.Path == currentTrack.Path {
-					p.currentIndex = i
-					break
-				}
-			}
-		}
-	} else {
-		if p.shuffledTracks != nil && p.currentIndex < len(p.shuffledTracks) {
-			currentTrack := p.shuffledTracks[p.currentIndex]
-			for i, track := range p.tracks {
-				if track.Path == currentTrack.Path {
-					p.currentIndex = i
-					break
-				}
-			}
-		}
-		p.shuffledTracks = nil
-	}
-}
-
 func (p *Player) ToggleRepeat() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.repeatMode = (p.repeatMode + 1) % 3
+	p.prepareNext()
 }
 
 func (p *Player) SetRepeatMode(mode RepeatMode) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.repeatMode = mode
+	p.prepareNext()
 }
 
 func (p *Player) GetRepeatMode() RepeatMode {
@@ -332,6 +869,15 @@ func (p *Player) GetRepeatMode() RepeatMode {
 
 func (p *Player) HandleTrackEnd() error {
 	p.mu.Lock()
+	if p.stopAfter != StopAfterNone {
+		if upcoming, ok := p.peekNext(); !ok || p.stopsAfter(upcoming.track) {
+			p.stopAfter = StopAfterNone
+			p.mu.Unlock()
+			p.Stop()
+			return nil
+		}
+	}
+
 	mode := p.repeatMode
 	playlist := p.getCurrentPlaylist()
 	p.mu.Unlock()
@@ -344,7 +890,7 @@ func (p *Player) HandleTrackEnd() error {
 		return p.Next()
 	default:
 		p.mu.Lock()
-		if p.currentIndex < len(playlist)-1 {
+		if len(p.queue) > 0 || p.currentIndex < len(playlist)-1 {
 			p.mu.Unlock()
 			return p.Next()
 		}
@@ -358,11 +904,10 @@ func (p *Player) GetCurrentTime() time.Duration {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.streamer != nil && p.playing {
-		speaker.Lock()
-		pos := p.streamer.Position()
-		speaker.Unlock()
-		p.currentTime = time.Duration(float64(pos) / float64(p.format.SampleRate) * float64(time.Second))
+	if p.source != nil && p.playing {
+		p.output.Lock()
+		p.currentTime = p.source.currentTime()
+		p.output.Unlock()
 	}
 
 	return p.currentTime
@@ -378,11 +923,8 @@ func (p *Player) GetCurrentTrack() Track {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	playlist := p.getCurrentPlaylist()
-	if p.currentIndex < len(playlist) {
-		return playlist[p.currentIndex]
-	}
-	return Track{}
+	track, _ := p.currentTrack()
+	return track
 }
 
 func (p *Player) IsPlaying() bool {
@@ -394,7 +936,7 @@ func (p *Player) IsPlaying() bool {
 func (p *Player) GetShuffle() bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	return p.shuffle
+	return p.shuffling()
 }
 
 func (p *Player) GetCurrentIndex() int {
@@ -423,12 +965,21 @@ func (p *Player) SetVolume(volume float64) {
 	}
 
 	p.volume = volume
-	if p.volumeCtrl != nil {
-		speaker.Lock()
-		p.volumeCtrl.Volume = p.volumeToDecibels(volume)
-		p.volumeCtrl.Silent = (volume < 0.0001)
-		speaker.Unlock()
+	p.applyVolume()
+
+	p.emit(PlayerEvent{Type: EventVolumeChanged, Volume: volume})
+}
+
+func (p *Player) applyVolume() {
+	if p.volumeCtrl == nil {
+		return
 	}
+
+	volume := p.volume * p.fadeLevel
+	p.output.Lock()
+	p.volumeCtrl.Volume = p.volumeToDecibels(volume)
+	p.volumeCtrl.Silent = (volume < 0.0001)
+	p.output.Unlock()
 }
 
 func (p *Player) GetVolume() float64 {
@@ -437,52 +988,111 @@ func (p *Player) GetVolume() float64 {
 	return p.volume
 }
 
+func (p *Player) SetSeekSteps(small, large time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if small > 0 {
+		p.seekStep = small
+	}
+	if large > 0 {
+		p.largeSeekStep = large
+	}
+}
+
+func (p *Player) GetSeekSteps() (time.Duration, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seekStep, p.largeSeekStep
+}
+
 func (p *Player) SeekForward() error {
-	return p.seek(10 * time.Second)
+	return p.seekByStep(false, 1)
 }
 
 func (p *Player) SeekBackward() error {
-	return p.seek(-10 * time.Second)
+	return p.seekByStep(false, -1)
 }
 
-func (p *Player) seek(offset time.Duration) error {
+func (p *Player) SeekForwardLarge() error {
+	return p.seekByStep(true, 1)
+}
+
+func (p *Player) SeekBackwardLarge() error {
+	return p.seekByStep(true, -1)
+}
+
+func (p *Player) seekByStep(large bool, direction time.Duration) error {
+	p.mu.Lock()
+	step := p.seekStep
+	if large {
+		step = p.largeSeekStep
+	}
+	p.mu.Unlock()
+
+	return p.SeekBy(direction * step)
+}
+
+func (p *Player) SeekBy(offset time.Duration) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.streamer == nil || !p.playing {
+	if !p.canSeek() {
 		return fmt.Errorf("no track is currently playing")
 	}
 
-	speaker.Lock()
-	defer speaker.Unlock()
+	p.output.Lock()
+	current := p.source.currentTime()
+	p.output.Unlock()
 
-	currentPos := p.streamer.Position()
-	currentTime := time.Duration(float64(currentPos) / float64(p.format.SampleRate) * float64(time.Second))
-	newTime := currentTime + offset
+	return p.seekTo(current + offset)
+}
 
-	if newTime < 0 {
-		newTime = 0
+func (p *Player) SeekTo(position time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.canSeek() {
+		return fmt.Errorf("no track is currently playing")
 	}
 
-	if newTime > p.totalTime {
-		newTime = p.totalTime
+	return p.seekTo(position)
+}
+
+func (p *Player) canSeek() bool {
+	return p.source != nil && (p.playing || p.paused)
+}
+
+func (p *Player) seekTo(position time.Duration) error {
+	if position < 0 {
+		position = 0
 	}
 
-	newPos := int(float64(newTime) / float64(time.Second) * float64(p.format.SampleRate))
+	if position > p.totalTime {
+		position = p.totalTime
+	}
+
+	p.output.Lock()
+	defer p.output.Unlock()
+
+	newPos := p.source.durationToSamples(position)
 
 	if newPos < 0 {
 		newPos = 0
 	}
 
-	if newPos >= p.streamer.Len() {
-		newPos = p.streamer.Len() - 1
+	if newPos >= p.source.streamer.Len() {
+		newPos = p.source.streamer.Len() - 1
 	}
 
-	if err := p.streamer.Seek(newPos); err != nil {
+	if err := p.source.streamer.Seek(newPos); err != nil {
 		return fmt.Errorf("failed to seek: %v", err)
 	}
+	p.gapless.rewindNext()
+	p.speedCtrl.reset()
 
-	p.currentTime = newTime
+	p.currentTime = p.source.samplesToDuration(newPos)
+	p.emit(PlayerEvent{Type: EventSeeked, Track: p.source.track, Index: p.currentIndex, Position: p.currentTime})
 	return nil
 }
 
@@ -494,11 +1104,10 @@ func (p *Player) GetProgress() float64 {
 		return 0.0
 	}
 
-	if p.streamer != nil && p.playing {
-		speaker.Lock()
-		pos := p.streamer.Position()
-		speaker.Unlock()
-		p.currentTime = time.Duration(float64(pos) / float64(p.format.SampleRate) * float64(time.Second))
+	if p.source != nil && p.playing {
+		p.output.Lock()
+		p.currentTime = p.source.currentTime()
+		p.output.Unlock()
 	}
 
 	return float64(p.currentTime) / float64(p.totalTime)
@@ -508,7 +1117,7 @@ func (p *Player) SeekToPosition(position float64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.streamer == nil || !p.playing {
+	if !p.canSeek() {
 		return fmt.Errorf("no track is currently playing")
 	}
 
@@ -520,14 +1129,5 @@ func (p *Player) SeekToPosition(position float64) error {
 		position = 1.0
 	}
 
-	speaker.Lock()
-	defer speaker.Unlock()
-
-	newPos := int(position * float64(p.streamer.Len()))
-	if err := p.streamer.Seek(newPos); err != nil {
-		return fmt.Errorf("failed to seek: %v", err)
-	}
-
-	p.currentTime = time.Duration(float64(newPos) / float64(p.format.SampleRate) * float64(time.Second))
-	return nil
+	return p.seekTo(time.Duration(position * float64(p.totalTime)))
 }
@@ -0,0 +1,758 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/generators"
+	"github.com/faiface/beep/wav"
+)
+
+const testTrackLength = 500 * time.Millisecond
+
+func writeTestTrack(t *testing.T, dir, name string, length time.Duration) string {
+	t.Helper()
+	return writeTestTrackAt(t, dir, name, length, defaultOutputSampleRate)
+}
+
+func writeTestTrackAt(t *testing.T, dir, name string, length time.Duration, rate beep.SampleRate) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	format := beep.Format{SampleRate: rate, NumChannels: 2, Precision: 2}
+	tone, err := generators.SinTone(format.SampleRate, 440)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wav.Encode(file, beep.Take(format.SampleRate.N(length), tone), format); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestPlayer(t *testing.T, count int) (*Player, *NullOutput, <-chan PlayerEvent) {
+	t.Helper()
+
+	dir := t.TempDir()
+	tracks := make([]Track, count)
+	for i := range tracks {
+		name := fmt.Sprintf("track%d.wav", i)
+		tracks[i] = Track{Path: writeTestTrack(t, dir, name, testTrackLength), Title: name}
+	}
+
+	output := NewNullOutput(0)
+	player := NewPlayerWithOutput(tracks, output)
+	events, unsubscribe := player.Subscribe()
+
+	t.Cleanup(func() {
+		unsubscribe()
+		player.Close()
+	})
+
+	return player, output, events
+}
+
+func waitForEvent(t *testing.T, events <-chan PlayerEvent, eventType EventType) PlayerEvent {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s event", eventType)
+		}
+	}
+}
+
+func assertDuration(t *testing.T, name string, got, want time.Duration) {
+	t.Helper()
+
+	const tolerance = 20 * time.Millisecond
+	if got < want-tolerance || got > want+tolerance {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestPlayStartsFirstTrack(t *testing.T) {
+	player, output, events := newTestPlayer(t, 3)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+
+	event := waitForEvent(t, events, EventTrackStarted)
+	if event.Index != 0 {
+		t.Fatalf("started index = %d, want 0", event.Index)
+	}
+	assertDuration(t, "duration", event.Duration, testTrackLength)
+
+	if !player.IsPlaying() {
+		t.Fatal("player is not playing")
+	}
+
+	output.Advance(200 * time.Millisecond)
+	assertDuration(t, "current time", player.GetCurrentTime(), 200*time.Millisecond)
+}
+
+func TestPauseAndResume(t *testing.T) {
+	player, output, events := newTestPlayer(t, 1)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	output.Advance(100 * time.Millisecond)
+
+	player.Pause()
+	waitForEvent(t, events, EventPaused)
+
+	output.Advance(200 * time.Millisecond)
+	assertDuration(t, "paused time", player.GetCurrentTime(), 100*time.Millisecond)
+
+	player.Resume()
+	waitForEvent(t, events, EventResumed)
+
+	output.Advance(200 * time.Millisecond)
+	assertDuration(t, "resumed time", player.GetCurrentTime(), 300*time.Millisecond)
+}
+
+func TestNextAndPrevious(t *testing.T) {
+	player, _, events := newTestPlayer(t, 3)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ended := waitForEvent(t, events, EventTrackEnded)
+	if ended.Index != 0 || ended.Completed {
+		t.Fatalf("ended = index %d completed %v, want index 0 not completed", ended.Index, ended.Completed)
+	}
+	if index := player.GetCurrentIndex(); index != 1 {
+		t.Fatalf("index after Next = %d, want 1", index)
+	}
+
+	if err := player.Previous(); err != nil {
+		t.Fatal(err)
+	}
+	if index := player.GetCurrentIndex(); index != 0 {
+		t.Fatalf("index after Previous = %d, want 0", index)
+	}
+
+	if err := player.Previous(); err != nil {
+		t.Fatal(err)
+	}
+	if index := player.GetCurrentIndex(); index != 2 {
+		t.Fatalf("index after wrapping Previous = %d, want 2", index)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	player, _, events := newTestPlayer(t, 3)
+
+	if err := player.Skip(2); err != nil {
+		t.Fatal(err)
+	}
+
+	event := waitForEvent(t, events, EventTrackStarted)
+	if event.Index != 2 || player.GetCurrentTrack().Title != "track2.wav" {
+		t.Fatalf("skipped to %d (%s), want 2", event.Index, player.GetCurrentTrack().Title)
+	}
+
+	if err := player.Skip(3); err == nil {
+		t.Fatal("expected an error when skipping out of range")
+	}
+}
+
+func TestAdvancesToNextTrack(t *testing.T) {
+	player, output, events := newTestPlayer(t, 2)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+
+	output.Advance(testTrackLength + 100*time.Millisecond)
+
+	ended := waitForEvent(t, events, EventTrackEnded)
+	if ended.Index != 0 || !ended.Completed {
+		t.Fatalf("ended = index %d completed %v, want index 0 completed", ended.Index, ended.Completed)
+	}
+
+	started := waitForEvent(t, events, EventTrackStarted)
+	if started.Index != 1 {
+		t.Fatalf("started index = %d, want 1", started.Index)
+	}
+	assertDuration(t, "current time", player.GetCurrentTime(), 100*time.Millisecond)
+}
+
+func TestReplaceTracksKeepsCurrentTrack(t *testing.T) {
+	player, output, events := newTestPlayer(t, 3)
+	tracks := player.tracks
+
+	if err := player.Skip(1); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+	output.Advance(100 * time.Millisecond)
+
+	moved := tracks[1]
+	moved.Path = filepath.Join(filepath.Dir(moved.Path), "moved.wav")
+	if err := os.Rename(tracks[1].Path, moved.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	player.ReplaceTracks([]Track{moved, tracks[0], tracks[2]})
+	if index, current := player.GetCurrentIndex(), player.GetCurrentTrack(); index != 0 || current.Path != moved.Path {
+		t.Fatalf("current = %d (%s), want the moved track at 0", index, current.Path)
+	}
+
+	// An untagged file must not be taken for another one by its empty tags.
+	player.ReplaceTracks([]Track{{Path: moved.Path}, tracks[0], tracks[2]})
+	untagged := Track{Path: writeTestTrack(t, t.TempDir(), "other.wav", testTrackLength)}
+	player.ReplaceTracks([]Track{untagged, tracks[0], tracks[2]})
+	if current := player.GetCurrentTrack(); current.Path != moved.Path || !player.IsPlayingQueued() {
+		t.Fatalf("current = %s, want the removed track playing on its own", current.Path)
+	}
+	for _, track := range player.State().Context {
+		if track.Path == moved.Path {
+			t.Fatal("the removed track is still in the context")
+		}
+	}
+
+	output.Advance(100 * time.Millisecond)
+	if !player.IsPlaying() {
+		t.Fatal("playback stopped")
+	}
+	assertDuration(t, "current time", player.GetCurrentTime(), 200*time.Millisecond)
+
+	output.Advance(testTrackLength)
+	started := waitForEvent(t, events, EventTrackStarted)
+	if started.Index != 0 || started.Track.Path != untagged.Path {
+		t.Fatalf("started %d (%s), want the context to carry on at 0", started.Index, started.Track.Path)
+	}
+}
+
+func TestOutputSampleRate(t *testing.T) {
+	dir := t.TempDir()
+	tracks := []Track{
+		{Path: writeTestTrackAt(t, dir, "first.wav", testTrackLength, 48000), Title: "first.wav"},
+		{Path: writeTestTrackAt(t, dir, "second.wav", testTrackLength, 48000), Title: "second.wav"},
+	}
+
+	output := NewNullOutput(0)
+	player := NewPlayerWithOutput(tracks, output)
+	events, unsubscribe := player.Subscribe()
+	t.Cleanup(func() {
+		unsubscribe()
+		player.Close()
+	})
+
+	player.SetOutputSampleRate(48000)
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	started := waitForEvent(t, events, EventTrackStarted)
+	assertDuration(t, "duration", started.Duration, testTrackLength)
+	if output.rate != 48000 || player.SampleRate() != 48000 {
+		t.Fatalf("output rate = %d, player rate = %d, want 48000", output.rate, player.SampleRate())
+	}
+
+	output.Advance(200 * time.Millisecond)
+	assertDuration(t, "current time", player.GetCurrentTime(), 200*time.Millisecond)
+
+	// Changing the rate mid-track must not retune what is already playing.
+	player.SetOutputSampleRate(defaultOutputSampleRate)
+	if rate := player.GetOutputSampleRate(); rate != defaultOutputSampleRate {
+		t.Fatalf("requested rate = %d, want %d", rate, defaultOutputSampleRate)
+	}
+	if output.rate != 48000 || player.SampleRate() != 48000 {
+		t.Fatalf("rate changed mid-track to %d", player.SampleRate())
+	}
+	output.Advance(100 * time.Millisecond)
+	assertDuration(t, "current time", player.GetCurrentTime(), 300*time.Millisecond)
+
+	output.Advance(testTrackLength)
+	waitForEvent(t, events, EventTrackEnded)
+	started = waitForEvent(t, events, EventTrackStarted)
+	if started.Index != 1 {
+		t.Fatalf("started index = %d, want 1", started.Index)
+	}
+	if output.rate != defaultOutputSampleRate || player.SampleRate() != int(defaultOutputSampleRate) {
+		t.Fatalf("output rate after the track boundary = %d, want %d", output.rate, defaultOutputSampleRate)
+	}
+	assertDuration(t, "duration", started.Duration, testTrackLength)
+}
+
+func TestSeek(t *testing.T) {
+	player, output, events := newTestPlayer(t, 1)
+
+	if err := player.SeekForward(); err == nil {
+		t.Fatal("expected an error when seeking while stopped")
+	}
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.SeekToPosition(0.5); err != nil {
+		t.Fatal(err)
+	}
+	event := waitForEvent(t, events, EventSeeked)
+	assertDuration(t, "seek position", event.Position, testTrackLength/2)
+
+	output.Advance(100 * time.Millisecond)
+	assertDuration(t, "current time", player.GetCurrentTime(), testTrackLength/2+100*time.Millisecond)
+
+	if err := player.SeekBackward(); err != nil {
+		t.Fatal(err)
+	}
+	assertDuration(t, "current time", player.GetCurrentTime(), 0)
+}
+
+func TestSeekToWhilePaused(t *testing.T) {
+	player, output, events := newTestPlayer(t, 1)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	player.Pause()
+
+	if err := player.SeekTo(300 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventSeeked)
+	assertDuration(t, "paused seek", player.GetCurrentTime(), 300*time.Millisecond)
+
+	player.SetSeekSteps(100*time.Millisecond, time.Minute)
+	if err := player.SeekBackward(); err != nil {
+		t.Fatal(err)
+	}
+	assertDuration(t, "stepped back", player.GetCurrentTime(), 200*time.Millisecond)
+
+	player.Resume()
+	output.Advance(100 * time.Millisecond)
+	assertDuration(t, "resumed time", player.GetCurrentTime(), 300*time.Millisecond)
+}
+
+func TestABLoop(t *testing.T) {
+	player, output, events := newTestPlayer(t, 2)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	if err := player.SetLoopEnd(); err == nil {
+		t.Fatal("expected an error when setting B before A")
+	}
+
+	output.Advance(100 * time.Millisecond)
+	if err := player.SetLoopStart(); err != nil {
+		t.Fatal(err)
+	}
+	output.Advance(200 * time.Millisecond)
+	if err := player.SetLoopEnd(); err != nil {
+		t.Fatal(err)
+	}
+
+	loop := player.GetLoop()
+	if !loop.Active {
+		t.Fatal("loop is not active")
+	}
+	assertDuration(t, "loop start", loop.Start, 100*time.Millisecond)
+	assertDuration(t, "loop end", loop.End, 300*time.Millisecond)
+
+	output.Advance(2 * testTrackLength)
+	if index := player.GetCurrentIndex(); index != 0 {
+		t.Fatalf("loop let playback advance to track %d", index)
+	}
+	if current := player.GetCurrentTime(); current < loop.Start || current > loop.End {
+		t.Fatalf("current time %v is outside the loop", current)
+	}
+
+	player.ClearLoop()
+	output.Advance(testTrackLength)
+
+	started := waitForEvent(t, events, EventTrackStarted)
+	for started.Index != 1 {
+		started = waitForEvent(t, events, EventTrackStarted)
+	}
+}
+
+func TestPlaybackSpeed(t *testing.T) {
+	player, output, _ := newTestPlayer(t, 1)
+
+	player.SetSpeed(5)
+	if speed := player.GetSpeed(); speed != MaxPlaybackSpeed {
+		t.Fatalf("speed = %v, want it clamped to %v", speed, MaxPlaybackSpeed)
+	}
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	output.Advance(100 * time.Millisecond)
+
+	current := player.GetCurrentTime()
+	if current < 190*time.Millisecond || current > 250*time.Millisecond {
+		t.Fatalf("track time after 100ms at 2x = %v, want about 200ms", current)
+	}
+
+	// The decoder runs ahead by what the time stretcher holds, but a loop
+	// point lands on what is being heard.
+	if err := player.SetLoopStart(); err != nil {
+		t.Fatal(err)
+	}
+	assertDuration(t, "loop start at 2x", player.GetLoop().Start, 200*time.Millisecond)
+
+	player.TogglePreservePitch()
+	if player.GetPreservePitch() {
+		t.Fatal("pitch preservation is still enabled")
+	}
+
+	player.SetSpeed(MinPlaybackSpeed)
+	before := player.GetCurrentTime()
+	output.Advance(100 * time.Millisecond)
+	assertDuration(t, "track time after 100ms at 0.5x", player.GetCurrentTime()-before, 50*time.Millisecond)
+}
+
+func TestStopAfterTrack(t *testing.T) {
+	player, output, events := newTestPlayer(t, 2)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+	player.SetStopAfter(StopAfterTrack)
+
+	output.Advance(testTrackLength + 100*time.Millisecond)
+	ended := waitForEvent(t, events, EventTrackEnded)
+	if ended.Index != 0 || !ended.Completed {
+		t.Fatalf("ended = index %d completed %v, want index 0 completed", ended.Index, ended.Completed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for player.IsPlaying() {
+		if time.Now().After(deadline) {
+			t.Fatal("player kept playing after the current track")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if index := player.GetCurrentIndex(); index != 0 {
+		t.Fatalf("player moved on to track %d", index)
+	}
+	if mode := player.GetStopAfter(); mode != StopAfterNone {
+		t.Fatalf("stop after mode = %s, want it reset", mode)
+	}
+}
+
+// fakeClock only moves when the test advances it, and never ticks: the test
+// checks the sleep timer itself after each step.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Tick(time.Duration) (<-chan time.Time, func()) {
+	return nil, func() {}
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestSleepTimer(t *testing.T) {
+	player, _, events := newTestPlayer(t, 1)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	player.clock = clock
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	player.SetSleepTimer(400*time.Millisecond, true)
+
+	remaining, active := player.GetSleepTimer()
+	if !active || remaining != 400*time.Millisecond {
+		t.Fatalf("sleep timer = %v, %v; want 400ms active", remaining, active)
+	}
+
+	player.mu.Lock()
+	done := player.sleepDone
+	player.mu.Unlock()
+
+	clock.advance(300 * time.Millisecond)
+	if player.checkSleepTimer(done) {
+		t.Fatal("sleep timer finished early")
+	}
+	player.mu.Lock()
+	level := player.fadeLevel
+	player.mu.Unlock()
+	if level != 0.25 {
+		t.Fatalf("fade level with 100ms of 400ms left = %v, want 0.25", level)
+	}
+
+	clock.advance(100 * time.Millisecond)
+	if !player.checkSleepTimer(done) {
+		t.Fatal("sleep timer did not finish at its deadline")
+	}
+
+	ended := waitForEvent(t, events, EventTrackEnded)
+	if ended.Completed {
+		t.Fatal("sleep timer reported the track as completed")
+	}
+	if player.IsPlaying() {
+		t.Fatal("player is still playing after the sleep timer expired")
+	}
+	if _, active := player.GetSleepTimer(); active {
+		t.Fatal("sleep timer is still active after expiring")
+	}
+	player.mu.Lock()
+	level = player.fadeLevel
+	player.mu.Unlock()
+	if level != 1 {
+		t.Fatalf("fade level after the timer = %v, want it restored to 1", level)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	player, output, _ := newTestPlayer(t, 3)
+
+	if err := player.Skip(1); err != nil {
+		t.Fatal(err)
+	}
+	player.Enqueue(player.tracks[2])
+	player.SetVolume(0.5)
+	player.SetRepeatMode(RepeatAll)
+	output.Advance(200 * time.Millisecond)
+
+	var session Session
+	session.SetPlaybackState(player.State())
+
+	restored := NewPlayerWithOutput(nil, NewNullOutput(0))
+	defer restored.Close()
+
+	if err := restored.RestoreState(session.PlaybackState(player.tracks, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if index := restored.GetCurrentIndex(); index != 1 {
+		t.Fatalf("restored index = %d, want 1", index)
+	}
+	if restored.IsPlaying() {
+		t.Fatal("restored session should start paused")
+	}
+	assertDuration(t, "restored position", restored.GetCurrentTime(), 200*time.Millisecond)
+
+	if queue := restored.GetQueue(); len(queue) != 1 || queue[0].Path != player.tracks[2].Path {
+		t.Fatalf("restored queue = %v, want track2", queue)
+	}
+	if restored.GetVolume() != 0.5 || restored.GetRepeatMode() != RepeatAll {
+		t.Fatalf("restored volume %v repeat %v", restored.GetVolume(), restored.GetRepeatMode())
+	}
+
+	// Without its paths the context is the one passed in.
+	session.Context = nil
+	context := player.tracks[1:]
+	if err := restored.RestoreState(session.PlaybackState(player.tracks, context)); err != nil {
+		t.Fatal(err)
+	}
+	if index, current := restored.GetCurrentIndex(), restored.GetCurrentTrack(); index != 0 || current.Path != player.tracks[1].Path {
+		t.Fatalf("restored %d (%s), want track1 at 0", index, current.Path)
+	}
+}
+
+func TestRepeatOff(t *testing.T) {
+	player, output, events := newTestPlayer(t, 2)
+
+	if err := player.Skip(1); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+
+	output.Advance(testTrackLength + 100*time.Millisecond)
+	waitForEvent(t, events, EventTrackEnded)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for player.IsPlaying() {
+		if time.Now().After(deadline) {
+			t.Fatal("player kept playing after the last track")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRepeatOne(t *testing.T) {
+	player, output, events := newTestPlayer(t, 2)
+	player.SetRepeatMode(RepeatOne)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+
+	output.Advance(testTrackLength + 100*time.Millisecond)
+
+	started := waitForEvent(t, events, EventTrackStarted)
+	if started.Index != 0 {
+		t.Fatalf("started index = %d, want 0", started.Index)
+	}
+}
+
+func TestRepeatAll(t *testing.T) {
+	player, output, events := newTestPlayer(t, 2)
+	player.SetRepeatMode(RepeatAll)
+
+	if err := player.Skip(1); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+
+	output.Advance(testTrackLength + 100*time.Millisecond)
+
+	started := waitForEvent(t, events, EventTrackStarted)
+	if started.Index != 0 {
+		t.Fatalf("started index = %d, want 0", started.Index)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	const count = 5
+	player, _, _ := newTestPlayer(t, count)
+
+	if err := player.Skip(2); err != nil {
+		t.Fatal(err)
+	}
+
+	player.CycleShuffle()
+	if player.GetShuffleMode() != ShuffleRandom {
+		t.Fatal("shuffle is not enabled")
+	}
+	if title := player.GetCurrentTrack().Title; title != "track2.wav" {
+		t.Fatalf("current track after shuffling = %s, want track2.wav", title)
+	}
+
+	seen := map[string]bool{player.GetCurrentTrack().Title: true}
+	for i := 1; i < count; i++ {
+		if err := player.Next(); err != nil {
+			t.Fatal(err)
+		}
+		seen[player.GetCurrentTrack().Title] = true
+	}
+	if len(seen) != count {
+		t.Fatalf("shuffle visited %d distinct tracks, want %d", len(seen), count)
+	}
+
+	player.SetShuffleMode(ShuffleOff)
+	current := player.GetCurrentTrack()
+	if player.tracks[player.GetCurrentIndex()].Path != current.Path {
+		t.Fatal("index does not point at the current track after unshuffling")
+	}
+}
+
+func TestShuffleStrategies(t *testing.T) {
+	var tracks []Track
+	for _, album := range []string{"A", "B", "C", "D"} {
+		for i := 0; i < 4; i++ {
+			tracks = append(tracks, Track{
+				Path:   fmt.Sprintf("/%s/%d.flac", album, i),
+				Title:  fmt.Sprintf("%s%d", album, i),
+				Album:  album,
+				Artist: "Artist " + album,
+			})
+		}
+	}
+	player := NewPlayerWithOutput(tracks, NewNullOutput(0))
+
+	player.SetShuffleMode(ShuffleAlbum)
+	shuffled := player.getCurrentPlaylist()
+	for i := 0; i < len(shuffled); i += 4 {
+		for j := 0; j < 4; j++ {
+			want := fmt.Sprintf("%s%d", shuffled[i].Album, j)
+			if shuffled[i+j].Title != want {
+				t.Fatalf("album shuffle position %d = %s, want %s", i+j, shuffled[i+j].Title, want)
+			}
+		}
+	}
+
+	player.SetShuffleMode(ShuffleArtistSpread)
+	shuffled = player.getCurrentPlaylist()
+	for i := 1; i < len(shuffled); i++ {
+		if shuffled[i].Artist == shuffled[i-1].Artist {
+			t.Fatalf("artist spread shuffle repeats %s at %d", shuffled[i].Artist, i)
+		}
+	}
+
+	player.SetShuffleMode(ShuffleSeeded)
+	state := player.State()
+	first := trackPaths(player.getCurrentPlaylist())
+
+	restored := NewPlayerWithOutput(nil, NewNullOutput(0))
+	defer restored.Close()
+	// The tracks don't exist on disk, so cueing fails, but the order is rebuilt.
+	restored.RestoreState(state)
+	if got := trackPaths(restored.getCurrentPlaylist()); strings.Join(got, ",") != strings.Join(first, ",") {
+		t.Fatal("seeded shuffle order changed after restoring the session")
+	}
+}
+
+func TestWAVOutput(t *testing.T) {
+	dir := t.TempDir()
+	track := Track{Path: writeTestTrack(t, dir, "tone.wav", testTrackLength)}
+
+	path := filepath.Join(dir, "out.wav")
+	output, err := NewWAVOutput(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	player := NewPlayerWithOutput([]Track{track}, output)
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	output.Advance(300 * time.Millisecond)
+
+	if err := player.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	streamer, format, err := wav.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.SampleRate != defaultOutputSampleRate || format.NumChannels != 2 {
+		t.Fatalf("format = %+v, want stereo at %d Hz", format, defaultOutputSampleRate)
+	}
+	if want := defaultOutputSampleRate.N(300 * time.Millisecond); streamer.Len() != want {
+		t.Fatalf("wrote %d samples, want %d", streamer.Len(), want)
+	}
+}
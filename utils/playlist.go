@@ -15,6 +15,7 @@ type Playlist struct {
 type PlaylistStore struct {
 	configDir string
 	playlists map[string]*Playlist
+	version   int
 }
 
 func NewPlaylistStore() (*PlaylistStore, error) {
@@ -74,6 +75,7 @@ func (ps *PlaylistStore) DeletePlaylist(name string) error {
 	}
 
 	delete(ps.playlists, name)
+	ps.version++
 	return os.Remove(ps.getPlaylistPath(name))
 }
 
@@ -93,6 +95,26 @@ func (ps *PlaylistStore) AddTrack(playlistName string, track Track) error {
 	return ps.savePlaylist(playlistName)
 }
 
+func (ps *PlaylistStore) AddTracks(playlistName string, tracks []Track) error {
+	playlist, exists := ps.playlists[playlistName]
+	if !exists {
+		return fmt.Errorf("playlist %s does not exist", playlistName)
+	}
+
+	seen := make(map[string]bool, len(playlist.Tracks))
+	for _, t := range playlist.Tracks {
+		seen[t.Path] = true
+	}
+
+	for _, track := range tracks {
+		if !seen[track.Path] {
+			seen[track.Path] = true
+			playlist.Tracks = append(playlist.Tracks, track)
+		}
+	}
+	return ps.savePlaylist(playlistName)
+}
+
 func (ps *PlaylistStore) RemoveTrack(playlistName string, index int) error {
 	playlist, exists := ps.playlists[playlistName]
 	if !exists {
@@ -123,12 +145,17 @@ func (ps *PlaylistStore) ListPlaylists() []string {
 	return names
 }
 
+func (ps *PlaylistStore) Version() int {
+	return ps.version
+}
+
 func (ps *PlaylistStore) savePlaylist(name string) error {
 	playlist, exists := ps.playlists[name]
 	if !exists {
 		return fmt.Errorf("playlist %s does not exist", name)
 	}
 
+	ps.version++
 	path := ps.getPlaylistPath(name)
 	data, err := json.MarshalIndent(playlist, "", "  ")
 	if err != nil {
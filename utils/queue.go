@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+)
+
+func (p *Player) Enqueue(track Track) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = append(p.queue, track)
+	p.prepareNext()
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+}
+
+func (p *Player) PlayNext(track Track) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = append([]Track{track}, p.queue...)
+	p.prepareNext()
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+}
+
+func (p *Player) RemoveFromQueue(index int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.queue) {
+		return fmt.Errorf("queue index out of range")
+	}
+
+	p.queue = append(p.queue[:index], p.queue[index+1:]...)
+	p.prepareNext()
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+	return nil
+}
+
+func (p *Player) MoveInQueue(from, to int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if from < 0 || from >= len(p.queue) || to < 0 || to >= len(p.queue) {
+		return fmt.Errorf("queue index out of range")
+	}
+
+	track := p.queue[from]
+	p.queue = append(p.queue[:from], p.queue[from+1:]...)
+	p.queue = append(p.queue[:to], append([]Track{track}, p.queue[to:]...)...)
+	p.prepareNext()
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+	return nil
+}
+
+func (p *Player) ClearQueue() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = nil
+	p.prepareNext()
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+}
+
+func (p *Player) GetQueue() []Track {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue := make([]Track, len(p.queue))
+	copy(queue, p.queue)
+	return queue
+}
+
+func (p *Player) IsPlayingQueued() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playingQueued
+}
+
+func (p *Player) SkipToQueued(index int) error {
+	p.mu.Lock()
+	if index < 0 || index >= len(p.queue) {
+		p.mu.Unlock()
+		return fmt.Errorf("queue index out of range")
+	}
+	p.mu.Unlock()
+
+	p.stop(EndSkipped)
+
+	p.mu.Lock()
+	if index >= len(p.queue) {
+		p.mu.Unlock()
+		return fmt.Errorf("queue index out of range")
+	}
+	p.queuedTrack = p.queue[index]
+	p.queue = append(p.queue[:index], p.queue[index+1:]...)
+	p.playingQueued = true
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+	p.mu.Unlock()
+
+	return p.Play()
+}
+
+func (p *Player) PlayContext(tracks []Track, index int) error {
+	p.Stop()
+
+	p.mu.Lock()
+	p.tracks = tracks
+	p.shuffledTracks = nil
+	if p.shuffling() {
+		p.createShuffledPlaylist()
+	}
+	p.currentIndex = 0
+	p.playingQueued = false
+	p.mu.Unlock()
+
+	return p.Skip(index)
+}
+
+// AppendTracks adds tracks to the end of the playing context. With shuffle on
+// they are also added to the end of the shuffled order.
+func (p *Player) AppendTracks(tracks []Track) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tracks = append(p.tracks[:len(p.tracks):len(p.tracks)], tracks...)
+	if p.shuffling() && p.shuffledTracks != nil {
+		p.shuffledTracks = append(p.shuffledTracks, tracks...)
+	}
+	p.prepareNext()
+}
+
+// ReplaceTracks swaps the playing context for an updated copy of it without
+// interrupting playback. The current track is found again by path, or by its
+// tags when it was moved. If it is gone it plays on as if it had been queued,
+// and the context then carries on with the track that followed it, which
+// leaves the current index at -1 when that is the first one. A shuffled order
+// is kept for the tracks that are still there.
+func (p *Player) ReplaceTracks(tracks []Track) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, hasCurrent := Track{}, false
+	if playlist := p.getCurrentPlaylist(); !p.playingQueued && p.currentIndex < len(playlist) {
+		current, hasCurrent = playlist[p.currentIndex], true
+	}
+
+	p.tracks = tracks
+	shuffled := p.shuffling() && p.shuffledTracks != nil
+	if shuffled {
+		p.shuffledTracks = reconcileTracks(p.shuffledTracks, tracks)
+	}
+
+	if hasCurrent {
+		playlist := p.getCurrentPlaylist()
+		index := trackIndex(playlist, current.Path)
+		if index < 0 && current.Title != "" {
+			index = slices.IndexFunc(playlist, func(track Track) bool {
+				return StatsKey(track) == StatsKey(current)
+			})
+		}
+		if index >= 0 {
+			p.currentIndex = index
+		} else {
+			p.queuedTrack = current
+			p.playingQueued = true
+			p.currentIndex = min(p.currentIndex, len(playlist)) - 1
+		}
+	}
+
+	p.prepareNext()
+}
+
+// reconcileTracks returns order with every track replaced by its copy in
+// tracks, dropping the ones tracks no longer has and adding its new ones at
+// the end.
+func reconcileTracks(order, tracks []Track) []Track {
+	byPath := make(map[string]Track, len(tracks))
+	for _, track := range tracks {
+		byPath[track.Path] = track
+	}
+
+	result := make([]Track, 0, len(tracks))
+	seen := make(map[string]bool, len(tracks))
+	for _, track := range order {
+		if fresh, ok := byPath[track.Path]; ok && !seen[track.Path] {
+			result = append(result, fresh)
+			seen[track.Path] = true
+		}
+	}
+	for _, track := range tracks {
+		if !seen[track.Path] {
+			result = append(result, track)
+		}
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func queuedTestTracks(t *testing.T, count int) []Track {
+	t.Helper()
+
+	dir := t.TempDir()
+	tracks := make([]Track, count)
+	for i := range tracks {
+		name := fmt.Sprintf("queued%d.wav", i)
+		tracks[i] = Track{Path: writeTestTrack(t, dir, name, testTrackLength), Title: name}
+	}
+	return tracks
+}
+
+func assertQueue(t *testing.T, player *Player, want ...string) {
+	t.Helper()
+
+	queue := player.GetQueue()
+	got := make([]string, len(queue))
+	for i, track := range queue {
+		got[i] = track.Title
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+}
+
+func assertPlaying(t *testing.T, player *Player, events <-chan PlayerEvent, title string, queued bool) {
+	t.Helper()
+
+	started := waitForEvent(t, events, EventTrackStarted)
+	if started.Track.Title != title {
+		t.Fatalf("started %q, want %q", started.Track.Title, title)
+	}
+	if got := player.IsPlayingQueued(); got != queued {
+		t.Fatalf("%q playing queued = %v, want %v", title, got, queued)
+	}
+}
+
+func TestQueueOrdering(t *testing.T) {
+	player, _, _ := newTestPlayer(t, 1)
+	queued := queuedTestTracks(t, 4)
+
+	player.Enqueue(queued[0])
+	player.Enqueue(queued[1])
+	player.PlayNext(queued[2])
+	assertQueue(t, player, "queued2.wav", "queued0.wav", "queued1.wav")
+
+	player.PlayNext(queued[3])
+	assertQueue(t, player, "queued3.wav", "queued2.wav", "queued0.wav", "queued1.wav")
+
+	if err := player.MoveInQueue(0, 3); err != nil {
+		t.Fatal(err)
+	}
+	assertQueue(t, player, "queued2.wav", "queued0.wav", "queued1.wav", "queued3.wav")
+
+	if err := player.MoveInQueue(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	assertQueue(t, player, "queued1.wav", "queued2.wav", "queued0.wav", "queued3.wav")
+
+	for _, move := range [][2]int{{-1, 0}, {0, 4}, {4, 0}} {
+		if err := player.MoveInQueue(move[0], move[1]); err == nil {
+			t.Errorf("MoveInQueue(%d, %d) expected an error", move[0], move[1])
+		}
+	}
+
+	if err := player.RemoveFromQueue(1); err != nil {
+		t.Fatal(err)
+	}
+	assertQueue(t, player, "queued1.wav", "queued0.wav", "queued3.wav")
+
+	player.ClearQueue()
+	assertQueue(t, player)
+}
+
+func TestQueuePlaysBeforeContext(t *testing.T) {
+	player, output, events := newTestPlayer(t, 3)
+	queued := queuedTestTracks(t, 2)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	assertPlaying(t, player, events, "track0.wav", false)
+
+	player.Enqueue(queued[0])
+	player.PlayNext(queued[1])
+
+	// Both the manual skip and the gapless hand-over take from the queue
+	// front, and the context resumes after the track that was playing.
+	if err := player.Next(); err != nil {
+		t.Fatal(err)
+	}
+	assertPlaying(t, player, events, "queued1.wav", true)
+	assertQueue(t, player, "queued0.wav")
+
+	output.Advance(testTrackLength + 50*time.Millisecond)
+	assertPlaying(t, player, events, "queued0.wav", true)
+	assertQueue(t, player)
+
+	output.Advance(testTrackLength + 50*time.Millisecond)
+	assertPlaying(t, player, events, "track1.wav", false)
+	if index := player.GetCurrentIndex(); index != 1 {
+		t.Fatalf("context index = %d, want 1", index)
+	}
+}
+
+func TestSkipToQueued(t *testing.T) {
+	player, _, events := newTestPlayer(t, 2)
+	queued := queuedTestTracks(t, 3)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	assertPlaying(t, player, events, "track0.wav", false)
+
+	for _, track := range queued {
+		player.Enqueue(track)
+	}
+
+	if err := player.SkipToQueued(1); err != nil {
+		t.Fatal(err)
+	}
+	assertPlaying(t, player, events, "queued1.wav", true)
+	assertQueue(t, player, "queued0.wav", "queued2.wav")
+
+	if err := player.SkipToQueued(2); err == nil {
+		t.Fatal("SkipToQueued past the end expected an error")
+	}
+
+	if err := player.Next(); err != nil {
+		t.Fatal(err)
+	}
+	assertPlaying(t, player, events, "queued0.wav", true)
+	assertQueue(t, player, "queued2.wav")
+}
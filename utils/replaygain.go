@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+type ReplayGainMode int
+
+const (
+	ReplayGainOff ReplayGainMode = iota
+	ReplayGainTrack
+	ReplayGainAlbum
+)
+
+func (r ReplayGainMode) String() string {
+	switch r {
+	case ReplayGainTrack:
+		return "track"
+	case ReplayGainAlbum:
+		return "album"
+	default:
+		return "off"
+	}
+}
+
+const r128ToReplayGainOffset = 5.0
+
+func readReplayGain(raw map[string]interface{}, track *Track) {
+	var r128Track, r128Album float64
+	var hasTrack, hasAlbum, hasR128Track, hasR128Album bool
+
+	for key, value := range raw {
+		name, text := replayGainField(key, value)
+
+		switch strings.ToLower(name) {
+		case "replaygain_track_gain":
+			if gain, ok := parseGain(text); ok {
+				track.TrackGain = gain
+				hasTrack = true
+			}
+		case "replaygain_track_peak":
+			if peak, ok := parseGain(text); ok {
+				track.TrackPeak = peak
+			}
+		case "replaygain_album_gain":
+			if gain, ok := parseGain(text); ok {
+				track.AlbumGain = gain
+				hasAlbum = true
+			}
+		case "replaygain_album_peak":
+			if peak, ok := parseGain(text); ok {
+				track.AlbumPeak = peak
+			}
+		case "r128_track_gain":
+			if q, err := strconv.Atoi(strings.TrimSpace(text)); err == nil {
+				r128Track = float64(q)/256 + r128ToReplayGainOffset
+				hasR128Track = true
+			}
+		case "r128_album_gain":
+			if q, err := strconv.Atoi(strings.TrimSpace(text)); err == nil {
+				r128Album = float64(q)/256 + r128ToReplayGainOffset
+				hasR128Album = true
+			}
+		}
+	}
+
+	if !hasTrack && hasR128Track {
+		track.TrackGain = r128Track
+	}
+	if !hasAlbum && hasR128Album {
+		track.AlbumGain = r128Album
+	}
+}
+
+func replayGainField(key string, value interface{}) (string, string) {
+	switch v := value.(type) {
+	case string:
+		return key, v
+	case *tag.Comm:
+		return v.Description, v.Text
+	case tag.Comm:
+		return v.Description, v.Text
+	}
+	return key, ""
+}
+
+func parseGain(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	if len(text) > 2 && strings.EqualFold(text[len(text)-2:], "db") {
+		text = strings.TrimSpace(text[:len(text)-2])
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func replayGainDecibels(track Track, mode ReplayGainMode, preamp float64) float64 {
+	gain, peak := track.TrackGain, track.TrackPeak
+	switch mode {
+	case ReplayGainOff:
+		return 0
+	case ReplayGainAlbum:
+		if track.AlbumGain != 0 || track.AlbumPeak != 0 {
+			gain, peak = track.AlbumGain, track.AlbumPeak
+		}
+	}
+
+	gain += preamp
+	if peak > 0 && math.Pow(10, gain/20)*peak > 1 {
+		gain = -20 * math.Log10(peak)
+	}
+	return gain
+}
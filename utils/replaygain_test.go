@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+func TestParseGain(t *testing.T) {
+	tests := []struct {
+		input string
+		gain  float64
+		ok    bool
+	}{
+		{"-6.48 dB", -6.48, true},
+		{"+3.20 dB", 3.2, true},
+		{"-7.5dB", -7.5, true},
+		{" 1.5 DB ", 1.5, true},
+		{"0.988553", 0.988553, true},
+		{"-2", -2, true},
+		{"", 0, false},
+		{"dB", 0, false},
+		{"loud", 0, false},
+	}
+
+	for _, tt := range tests {
+		gain, ok := parseGain(tt.input)
+		if gain != tt.gain || ok != tt.ok {
+			t.Errorf("parseGain(%q) = %v, %v; want %v, %v", tt.input, gain, ok, tt.gain, tt.ok)
+		}
+	}
+}
+
+func TestReadReplayGain(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       map[string]interface{}
+		trackGain float64
+		trackPeak float64
+		albumGain float64
+		albumPeak float64
+	}{
+		{
+			name: "vorbis comments",
+			raw: map[string]interface{}{
+				"REPLAYGAIN_TRACK_GAIN": "-6.50 dB",
+				"REPLAYGAIN_TRACK_PEAK": "0.95",
+				"replaygain_album_gain": "-4.25 dB",
+				"replaygain_album_peak": "0.99",
+			},
+			trackGain: -6.5, trackPeak: 0.95, albumGain: -4.25, albumPeak: 0.99,
+		},
+		{
+			name: "id3 comments",
+			raw: map[string]interface{}{
+				"TXXX":   &tag.Comm{Description: "REPLAYGAIN_TRACK_GAIN", Text: "-3 dB"},
+				"TXXX_0": tag.Comm{Description: "REPLAYGAIN_ALBUM_GAIN", Text: "-2 dB"},
+			},
+			trackGain: -3, albumGain: -2,
+		},
+		{
+			// R128 gains are Q7.8 fixed point relative to -23 LUFS, which
+			// is 5 dB quieter than the ReplayGain reference.
+			name: "r128",
+			raw: map[string]interface{}{
+				"R128_TRACK_GAIN": "-2560",
+				"R128_ALBUM_GAIN": "384",
+			},
+			trackGain: -5, albumGain: 6.5,
+		},
+		{
+			name: "replaygain wins over r128",
+			raw: map[string]interface{}{
+				"R128_TRACK_GAIN":       "-2560",
+				"REPLAYGAIN_TRACK_GAIN": "-1 dB",
+			},
+			trackGain: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		var track Track
+		readReplayGain(tt.raw, &track)
+		if track.TrackGain != tt.trackGain || track.TrackPeak != tt.trackPeak ||
+			track.AlbumGain != tt.albumGain || track.AlbumPeak != tt.albumPeak {
+			t.Errorf("%s: got track %v/%v album %v/%v, want track %v/%v album %v/%v", tt.name,
+				track.TrackGain, track.TrackPeak, track.AlbumGain, track.AlbumPeak,
+				tt.trackGain, tt.trackPeak, tt.albumGain, tt.albumPeak)
+		}
+	}
+}
+
+func TestReplayGainDecibels(t *testing.T) {
+	tagged := Track{TrackGain: -6, TrackPeak: 0.5, AlbumGain: -3, AlbumPeak: 0.5}
+	trackOnly := Track{TrackGain: -6, TrackPeak: 0.5}
+	loudPeak := Track{TrackGain: 4, TrackPeak: 0.9}
+
+	tests := []struct {
+		name   string
+		track  Track
+		mode   ReplayGainMode
+		preamp float64
+		want   float64
+	}{
+		{"off", tagged, ReplayGainOff, 6, 0},
+		{"track", tagged, ReplayGainTrack, 0, -6},
+		{"album", tagged, ReplayGainAlbum, 0, -3},
+		{"album falls back to track", trackOnly, ReplayGainAlbum, 0, -6},
+		{"preamp", tagged, ReplayGainTrack, 2, -4},
+		{"peak limits gain", loudPeak, ReplayGainTrack, 0, -20 * math.Log10(0.9)},
+		{"peak limits preamp", tagged, ReplayGainTrack, 15, -20 * math.Log10(0.5)},
+		{"no peak", Track{TrackGain: 4}, ReplayGainTrack, 0, 4},
+	}
+
+	for _, tt := range tests {
+		got := replayGainDecibels(tt.track, tt.mode, tt.preamp)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: replayGainDecibels = %v, want %v", tt.name, got, tt.want)
+		}
+		if tt.track.TrackPeak > 0 && math.Pow(10, got/20)*tt.track.TrackPeak > 1+1e-9 {
+			t.Errorf("%s: gain %v dB clips a peak of %v", tt.name, got, tt.track.TrackPeak)
+		}
+	}
+}
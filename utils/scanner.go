@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"io/fs"
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,70 +11,58 @@ import (
 )
 
 type Track struct {
-	Path     string
-	Title    string
-	Artist   string
-	Album    string
-	Duration time.Duration
-	Year     int
-	HasCover bool
-}
-
-var supportedExt = map[string]struct{}{
-	".mp3":  {},
-	".flac": {},
-	".wav":  {},
-	".ogg":  {},
+	Path      string
+	Title     string
+	Artist    string
+	Album     string
+	Duration  time.Duration
+	Year      int
+	HasCover  bool
+	TrackGain float64
+	TrackPeak float64
+	AlbumGain float64
+	AlbumPeak float64
+	File      string
+	Start     time.Duration
+	End       time.Duration
+	Indexes   []time.Duration
 }
 
 func isAudioFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
-	_, ok := supportedExt[ext]
+	_, ok := audioDecoders[ext]
 	return ok
 }
 
 func extractMetadata(path string) (Track, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return Track{Path: path}, nil
+		return Track{Path: path}, err
 	}
 	defer file.Close()
 
+	track := Track{Path: path}
+	track.Duration, _ = readDuration(file)
+
 	metadata, err := tag.ReadFrom(file)
 	if err != nil {
-		return Track{Path: path}, nil
+		return track, nil
 	}
 
-	track := Track{
-		Path:     path,
-		Title:    metadata.Title(),
-		Artist:   metadata.Artist(),
-		Album:    metadata.Album(),
-		Year:     metadata.Year(),
-		HasCover: metadata.Picture() != nil,
-	}
+	track.Title = metadata.Title()
+	track.Artist = metadata.Artist()
+	track.Album = metadata.Album()
+	track.Year = metadata.Year()
+	track.HasCover = metadata.Picture() != nil
+
+	readReplayGain(metadata.Raw(), &track)
 
 	return track, nil
 }
 
+// ScanDir returns the audio files under root. Files described by a CUE sheet
+// are replaced by the sheet's tracks.
 func ScanDir(root string) ([]Track, error) {
-	var tracks []Track
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-
-		if d.IsDir() {
-			return nil
-		}
-
-		if isAudioFile(path) {
-			track, _ := extractMetadata(path)
-			tracks = append(tracks, track)
-		}
-
-		return nil
-	})
-
-	return tracks, err
+	index, _, err := ScanLibrary(context.Background(), root, nil, ScanOptions{})
+	return index.Tracks(), err
 }
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSeekInput accepts an absolute timestamp such as "1:23:45", "3:05" or
+// "90", or a relative offset such as "+30s" or "-2m". Relative offsets are
+// returned with relative set and a signed duration.
+func ParseSeekInput(input string) (position time.Duration, relative bool, err error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, false, fmt.Errorf("empty seek position")
+	}
+
+	sign := time.Duration(1)
+	switch input[0] {
+	case '+':
+		relative = true
+		input = input[1:]
+	case '-':
+		relative = true
+		sign = -1
+		input = input[1:]
+	}
+
+	position, err = parseSeekDuration(strings.TrimSpace(input))
+	if err != nil {
+		return 0, false, err
+	}
+	return sign * position, relative, nil
+}
+
+func parseSeekDuration(input string) (time.Duration, error) {
+	if strings.Contains(input, ":") {
+		parts := strings.Split(input, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid timestamp %q", input)
+		}
+
+		var total time.Duration
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(part, 64)
+			if err != nil || value < 0 || (i > 0 && value >= 60) {
+				return 0, fmt.Errorf("invalid timestamp %q", input)
+			}
+			total = total*60 + time.Duration(value*float64(time.Second))
+		}
+		return total, nil
+	}
+
+	if seconds, err := strconv.ParseFloat(input, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	d, err := time.ParseDuration(input)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid seek position %q", input)
+	}
+	return d, nil
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSeekInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		position time.Duration
+		relative bool
+	}{
+		{"1:23:45", time.Hour + 23*time.Minute + 45*time.Second, false},
+		{"3:05", 3*time.Minute + 5*time.Second, false},
+		{"90", 90 * time.Second, false},
+		{"2.5", 2500 * time.Millisecond, false},
+		{"1m30s", 90 * time.Second, false},
+		{"+30s", 30 * time.Second, true},
+		{"-2m", -2 * time.Minute, true},
+		{" +1:00 ", time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		position, relative, err := ParseSeekInput(tt.input)
+		if err != nil {
+			t.Errorf("ParseSeekInput(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if position != tt.position || relative != tt.relative {
+			t.Errorf("ParseSeekInput(%q) = %v, %v; want %v, %v", tt.input, position, relative, tt.position, tt.relative)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "1:75", "1:2:3:4", "+", "--5s"} {
+		if _, _, err := ParseSeekInput(input); err == nil {
+			t.Errorf("ParseSeekInput(%q) expected an error", input)
+		}
+	}
+}
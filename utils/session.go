@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type PlaybackState struct {
+	Context       []Track
+	Index         int
+	Queue         []Track
+	QueuedTrack   Track
+	PlayingQueued bool
+	Position      time.Duration
+	Volume        float64
+	ShuffleMode   ShuffleMode
+	ShuffleSeed   int64
+	RepeatMode    RepeatMode
+}
+
+func (p *Player) State() PlaybackState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := PlaybackState{
+		Context:       append([]Track(nil), p.tracks...),
+		Index:         p.currentIndex,
+		Queue:         append([]Track(nil), p.queue...),
+		QueuedTrack:   p.queuedTrack,
+		PlayingQueued: p.playingQueued,
+		Position:      p.currentTime,
+		Volume:        p.volume,
+		ShuffleMode:   p.shuffleMode,
+		ShuffleSeed:   p.shuffleSeed,
+		RepeatMode:    p.repeatMode,
+	}
+
+	if p.shuffling() && p.shuffledTracks != nil && p.currentIndex >= 0 && p.currentIndex < len(p.shuffledTracks) {
+		state.Index = trackIndex(p.tracks, p.shuffledTracks[p.currentIndex].Path)
+	}
+
+	if p.source != nil {
+		p.output.Lock()
+		state.Position = p.source.currentTime()
+		p.output.Unlock()
+	}
+
+	return state
+}
+
+// RestoreState replaces the context, queue and playback settings and cues the
+// current track paused at the saved position.
+func (p *Player) RestoreState(state PlaybackState) error {
+	p.Stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tracks = state.Context
+	p.queue = append([]Track(nil), state.Queue...)
+	p.queuedTrack = state.QueuedTrack
+	p.playingQueued = state.PlayingQueued
+	p.shuffleMode = state.ShuffleMode
+	p.shuffleSeed = state.ShuffleSeed
+	p.shuffledTracks = nil
+	p.repeatMode = state.RepeatMode
+	p.volume = state.Volume
+	if p.volume < 0 || p.volume > 1 {
+		p.volume = 1
+	}
+
+	p.currentIndex = state.Index
+	if p.currentIndex < 0 || p.currentIndex >= len(p.tracks) {
+		p.currentIndex = 0
+	}
+
+	if p.shuffling() {
+		p.createShuffledPlaylist()
+		if p.currentIndex < len(p.tracks) {
+			if i := trackIndex(p.shuffledTracks, p.tracks[p.currentIndex].Path); i >= 0 {
+				p.currentIndex = i
+			}
+		}
+	}
+
+	p.emit(PlayerEvent{Type: EventQueueChanged})
+
+	if err := p.play(true); err != nil || p.source == nil {
+		return err
+	}
+	return p.seekTo(state.Position)
+}
+
+func trackIndex(tracks []Track, path string) int {
+	for i, track := range tracks {
+		if track.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+type SessionFilter struct {
+	Type  int
+	Key   string
+	Label string
+}
+
+type Session struct {
+	LibraryRoot    string
+	Context        []string
+	ContextLabel   string
+	ContextFilter  *SessionFilter
+	ContextTrack   string
+	Queue          []string
+	QueuedTrack    string
+	PlayingQueued  bool
+	Position       time.Duration
+	Volume         float64
+	ShuffleMode    ShuffleMode
+	ShuffleSeed    int64
+	RepeatMode     RepeatMode
+	Filter         SessionFilter
+	LibrarySection int
+	SelectedIndex  int
+	PlaylistIndex  int
+	AlbumIndex     int
+	ArtistIndex    int
+	HistoryIndex   int
+	StatsIndex     int
+}
+
+func (s *Session) SetPlaybackState(state PlaybackState) {
+	s.Context = trackPaths(state.Context)
+	s.Queue = trackPaths(state.Queue)
+	s.ContextTrack = ""
+	if state.Index >= 0 && state.Index < len(state.Context) {
+		s.ContextTrack = state.Context[state.Index].Path
+	}
+	s.QueuedTrack = ""
+	if state.PlayingQueued {
+		s.QueuedTrack = state.QueuedTrack.Path
+	}
+	s.PlayingQueued = state.PlayingQueued
+	s.Position = state.Position
+	s.Volume = state.Volume
+	s.ShuffleMode = state.ShuffleMode
+	s.ShuffleSeed = state.ShuffleSeed
+	s.RepeatMode = state.RepeatMode
+}
+
+// PlaybackState resolves the saved paths against library, skipping tracks that
+// are no longer there. A session saved without its context's paths plays
+// context, or the whole library when that is nil.
+func (s *Session) PlaybackState(library, context []Track) PlaybackState {
+	byPath := make(map[string]Track, len(library))
+	for _, track := range library {
+		byPath[track.Path] = track
+	}
+
+	resolve := func(paths []string) []Track {
+		tracks := make([]Track, 0, len(paths))
+		for _, path := range paths {
+			if track, ok := byPath[path]; ok {
+				tracks = append(tracks, track)
+			}
+		}
+		return tracks
+	}
+
+	state := PlaybackState{
+		Context:     resolve(s.Context),
+		Queue:       resolve(s.Queue),
+		Position:    s.Position,
+		Volume:      s.Volume,
+		ShuffleMode: s.ShuffleMode,
+		ShuffleSeed: s.ShuffleSeed,
+		RepeatMode:  s.RepeatMode,
+	}
+	if len(s.Context) == 0 {
+		state.Context = context
+	}
+	if state.Context == nil {
+		state.Context = library
+	}
+
+	state.Index = trackIndex(state.Context, s.ContextTrack)
+	if state.Index < 0 {
+		state.Index = 0
+		state.Position = 0
+	}
+
+	if queued, ok := byPath[s.QueuedTrack]; ok && s.PlayingQueued {
+		state.QueuedTrack = queued
+		state.PlayingQueued = true
+	} else if s.PlayingQueued {
+		state.Position = 0
+	}
+
+	return state
+}
+
+func trackPaths(tracks []Track) []string {
+	paths := make([]string, len(tracks))
+	for i, track := range tracks {
+		paths[i] = track.Path
+	}
+	return paths
+}
+
+func getSessionPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "session.json"), nil
+}
+
+func LoadSession() (*Session, error) {
+	path, err := getSessionPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func SaveSession(session *Session) error {
+	path, err := getSessionPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Settings are read from settings.json in the config directory. The file is
+// written with the defaults the first time so there is something to edit, and
+// keys missing from it keep their defaults.
+type Settings struct {
+	// OutputSampleRate is the rate the audio device is opened at. Tracks at
+	// other rates are resampled to it.
+	OutputSampleRate int
+
+	// SeekStepSeconds and LargeSeekStepSeconds are how far h/l and H/L seek.
+	SeekStepSeconds      int
+	LargeSeekStepSeconds int
+
+	// ReplayGainPreamp is added to the ReplayGain adjustment, in dB.
+	ReplayGainPreamp float64
+
+	// ScanWorkers is how many files a library scan reads in parallel. Zero
+	// picks a default.
+	ScanWorkers int
+
+	// LibraryPollSeconds is how often the library is walked for changes
+	// where they can't be watched for. Linux is told about them instead.
+	LibraryPollSeconds int
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		OutputSampleRate:     int(defaultOutputSampleRate),
+		SeekStepSeconds:      int(defaultSeekStep / time.Second),
+		LargeSeekStepSeconds: int(defaultLargeSeekStep / time.Second),
+		LibraryPollSeconds:   int(defaultLibraryPollInterval / time.Second),
+	}
+}
+
+func getSettingsPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "settings.json"), nil
+}
+
+func LoadSettings() (Settings, error) {
+	settings := DefaultSettings()
+
+	path, err := getSettingsPath()
+	if err != nil {
+		return settings, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, saveSettings(path, settings)
+	}
+	if err != nil {
+		return settings, err
+	}
+
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return DefaultSettings(), fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return settings, settings.validate()
+}
+
+func saveSettings(path string, settings Settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (s Settings) validRate() bool {
+	return s.OutputSampleRate >= 8000 && s.OutputSampleRate <= 384000
+}
+
+func (s Settings) validPreamp() bool {
+	return s.ReplayGainPreamp >= -15 && s.ReplayGainPreamp <= 15
+}
+
+func (s Settings) validate() error {
+	if !s.validRate() {
+		return fmt.Errorf("settings.json: OutputSampleRate %d is out of range", s.OutputSampleRate)
+	}
+	if s.SeekStepSeconds <= 0 || s.LargeSeekStepSeconds <= 0 {
+		return fmt.Errorf("settings.json: seek steps must be positive")
+	}
+	if !s.validPreamp() {
+		return fmt.Errorf("settings.json: ReplayGainPreamp %g is out of range", s.ReplayGainPreamp)
+	}
+	if s.ScanWorkers < 0 {
+		return fmt.Errorf("settings.json: ScanWorkers must not be negative")
+	}
+	if s.LibraryPollSeconds <= 0 {
+		return fmt.Errorf("settings.json: LibraryPollSeconds must be positive")
+	}
+	return nil
+}
+
+// LibraryPollInterval is LibraryPollSeconds, or the default when that is
+// invalid.
+func (s Settings) LibraryPollInterval() time.Duration {
+	if s.LibraryPollSeconds <= 0 {
+		return defaultLibraryPollInterval
+	}
+	return time.Duration(s.LibraryPollSeconds) * time.Second
+}
+
+// ApplySettings configures the player from s. Invalid values are left out.
+func (p *Player) ApplySettings(s Settings) {
+	if s.validRate() {
+		p.SetOutputSampleRate(beep.SampleRate(s.OutputSampleRate))
+	}
+	if s.validPreamp() {
+		p.SetPreamp(s.ReplayGainPreamp)
+	}
+	p.SetSeekSteps(time.Duration(s.SeekStepSeconds)*time.Second, time.Duration(s.LargeSeekStepSeconds)*time.Second)
+}
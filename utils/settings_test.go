@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLoadSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings != DefaultSettings() {
+		t.Fatalf("settings = %+v, want the defaults", settings)
+	}
+
+	path, err := getSettingsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("defaults were not written: %v", err)
+	}
+
+	// Keys left out of the file keep their defaults.
+	if err := os.WriteFile(path, []byte(`{"OutputSampleRate": 48000, "SeekStepSeconds": 10, "ReplayGainPreamp": 3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.OutputSampleRate != 48000 || settings.SeekStepSeconds != 10 ||
+		settings.LargeSeekStepSeconds != DefaultSettings().LargeSeekStepSeconds {
+		t.Fatalf("settings = %+v", settings)
+	}
+
+	player, _, _ := newTestPlayer(t, 1)
+	player.ApplySettings(settings)
+	if rate := player.GetOutputSampleRate(); rate != 48000 {
+		t.Fatalf("output rate = %d, want 48000", rate)
+	}
+	if small, large := player.GetSeekSteps(); small != 10*time.Second || large != defaultLargeSeekStep {
+		t.Fatalf("seek steps = %v, %v", small, large)
+	}
+	if preamp := player.GetPreamp(); preamp != 3 {
+		t.Fatalf("preamp = %v, want 3", preamp)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"OutputSampleRate": 12, "SeekStepSeconds": 0}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = LoadSettings()
+	if err == nil {
+		t.Fatal("out of range settings expected an error")
+	}
+	player.ApplySettings(settings)
+	if rate := player.GetOutputSampleRate(); rate != 48000 {
+		t.Fatalf("output rate = %d after an invalid setting, want 48000", rate)
+	}
+	if small, _ := player.GetSeekSteps(); small != 10*time.Second {
+		t.Fatalf("seek step = %v after an invalid setting, want 10s", small)
+	}
+}
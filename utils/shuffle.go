@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ShuffleMode int
+
+const (
+	ShuffleOff ShuffleMode = iota
+	ShuffleRandom
+	ShuffleAlbum
+	ShuffleArtistSpread
+	ShuffleWeighted
+	ShuffleSeeded
+
+	shuffleModeCount = 6
+)
+
+func (s ShuffleMode) String() string {
+	switch s {
+	case ShuffleRandom:
+		return "random"
+	case ShuffleAlbum:
+		return "album"
+	case ShuffleArtistSpread:
+		return "artist spread"
+	case ShuffleWeighted:
+		return "weighted"
+	case ShuffleSeeded:
+		return "seeded"
+	default:
+		return "off"
+	}
+}
+
+func (p *Player) shuffling() bool {
+	return p.shuffleMode != ShuffleOff
+}
+
+func (p *Player) newShuffledPlaylist() []Track {
+	shuffled := make([]Track, len(p.tracks))
+	copy(shuffled, p.tracks)
+
+	seed := time.Now().UnixNano()
+	if p.shuffleMode == ShuffleSeeded {
+		seed = p.shuffleSeed
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	switch p.shuffleMode {
+	case ShuffleAlbum:
+		return albumShuffle(shuffled, rng)
+	case ShuffleArtistSpread:
+		return artistSpreadShuffle(shuffled, rng)
+	case ShuffleWeighted:
+		return weightedShuffle(shuffled, p.shuffleWeight, rng)
+	default:
+		rng.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		return shuffled
+	}
+}
+
+func shuffleAlbumKey(track Track) string {
+	album := track.Album
+	if album == "" {
+		album = filepath.Dir(track.Path)
+	}
+	return strings.ToLower(album + "|" + track.Artist)
+}
+
+// albumShuffle plays albums in a random order, keeping each album's tracks in
+// their original order.
+func albumShuffle(tracks []Track, rng *rand.Rand) []Track {
+	var keys []string
+	albums := make(map[string][]Track)
+	for _, track := range tracks {
+		key := shuffleAlbumKey(track)
+		if _, ok := albums[key]; !ok {
+			keys = append(keys, key)
+		}
+		albums[key] = append(albums[key], track)
+	}
+
+	rng.Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+
+	shuffled := tracks[:0]
+	for _, key := range keys {
+		shuffled = append(shuffled, albums[key]...)
+	}
+	return shuffled
+}
+
+// artistSpreadShuffle spaces each artist's tracks evenly across the playlist
+// with a random offset, then swaps away any remaining back to back repeats.
+func artistSpreadShuffle(tracks []Track, rng *rand.Rand) []Track {
+	var keys []string
+	artists := make(map[string][]Track)
+	for _, track := range tracks {
+		key := strings.ToLower(track.Artist)
+		if _, ok := artists[key]; !ok {
+			keys = append(keys, key)
+		}
+		artists[key] = append(artists[key], track)
+	}
+
+	type placed struct {
+		track    Track
+		position float64
+	}
+	positions := make([]placed, 0, len(tracks))
+	for _, key := range keys {
+		group := artists[key]
+		rng.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+
+		step := 1 / float64(len(group))
+		offset := rng.Float64() * step
+		for i, track := range group {
+			jitter := (rng.Float64() - 0.5) * step * 0.2
+			positions = append(positions, placed{track: track, position: offset + float64(i)*step + jitter})
+		}
+	}
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].position < positions[j].position
+	})
+
+	shuffled := tracks[:0]
+	for _, item := range positions {
+		shuffled = append(shuffled, item.track)
+	}
+
+	sameArtist := func(a, b Track) bool {
+		return strings.EqualFold(a.Artist, b.Artist)
+	}
+	for i := 1; i < len(shuffled); i++ {
+		if !sameArtist(shuffled[i], shuffled[i-1]) {
+			continue
+		}
+		for j := i + 1; j < len(shuffled); j++ {
+			if sameArtist(shuffled[j], shuffled[i-1]) {
+				continue
+			}
+			if i+1 < len(shuffled) && j != i+1 && sameArtist(shuffled[j], shuffled[i+1]) {
+				continue
+			}
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			break
+		}
+	}
+	return shuffled
+}
+
+// weightedShuffle orders tracks so that heavier ones tend to come first, using
+// the Efraimidis-Spirakis key u^(1/w). Without a weight function every track
+// weighs the same.
+func weightedShuffle(tracks []Track, weight func(Track) float64, rng *rand.Rand) []Track {
+	keys := make(map[string]float64, len(tracks))
+	for _, track := range tracks {
+		w := 1.0
+		if weight != nil {
+			w = weight(track)
+		}
+		if w <= 0 {
+			w = math.SmallestNonzeroFloat64
+		}
+		keys[track.Path] = math.Pow(rng.Float64(), 1/w)
+	}
+
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return keys[tracks[i].Path] > keys[tracks[j].Path]
+	})
+	return tracks
+}
+
+// SetShuffleWeight sets how strongly the weighted shuffle favors each track.
+func (p *Player) SetShuffleWeight(weight func(Track) float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shuffleWeight = weight
+}
+
+func (p *Player) CycleShuffle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setShuffleMode((p.shuffleMode + 1) % shuffleModeCount)
+}
+
+func (p *Player) SetShuffleMode(mode ShuffleMode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setShuffleMode(mode)
+}
+
+// setShuffleMode rebuilds the shuffled playlist for mode, keeping the current
+// track playing at its new position. Entering seeded mode picks a new seed.
+func (p *Player) setShuffleMode(mode ShuffleMode) {
+	current, hasCurrent := Track{}, false
+	if playlist := p.getCurrentPlaylist(); p.currentIndex >= 0 && p.currentIndex < len(playlist) {
+		current, hasCurrent = playlist[p.currentIndex], true
+	}
+
+	if mode == ShuffleSeeded && p.shuffleMode != ShuffleSeeded {
+		p.shuffleSeed = time.Now().UnixNano()
+	}
+	p.shuffleMode = mode
+	p.shuffledTracks = nil
+	if p.shuffling() {
+		p.createShuffledPlaylist()
+	}
+
+	if hasCurrent {
+		if i := trackIndex(p.getCurrentPlaylist(), current.Path); i >= 0 {
+			p.currentIndex = i
+		}
+	}
+
+	p.prepareNext()
+}
+
+func (p *Player) GetShuffleMode() ShuffleMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.shuffleMode
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+type StopAfterMode int
+
+const (
+	StopAfterNone StopAfterMode = iota
+	StopAfterTrack
+	StopAfterAlbum
+)
+
+func (s StopAfterMode) String() string {
+	switch s {
+	case StopAfterTrack:
+		return "track"
+	case StopAfterAlbum:
+		return "album"
+	default:
+		return "off"
+	}
+}
+
+const (
+	sleepFadeDuration = time.Minute
+	sleepTickInterval = 100 * time.Millisecond
+)
+
+// clock is the time source for the sleep timer, so that tests can move time
+// forward instead of sleeping.
+type clock interface {
+	Now() time.Time
+	Tick(d time.Duration) (<-chan time.Time, func())
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Tick(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+var sleepTimerPresets = []time.Duration{
+	15 * time.Minute,
+	30 * time.Minute,
+	60 * time.Minute,
+}
+
+func (p *Player) SetSleepTimer(d time.Duration, fade bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancelSleepTimer()
+	if d <= 0 {
+		return
+	}
+
+	p.sleepDeadline = p.clock.Now().Add(d)
+	p.sleepFade = 0
+	if fade {
+		p.sleepFade = sleepFadeDuration
+		if d < p.sleepFade {
+			p.sleepFade = d
+		}
+	}
+
+	done := make(chan struct{})
+	p.sleepDone = done
+	go p.runSleepTimer(done)
+}
+
+func (p *Player) CancelSleepTimer() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cancelSleepTimer()
+}
+
+func (p *Player) cancelSleepTimer() {
+	if p.sleepDone != nil {
+		close(p.sleepDone)
+		p.sleepDone = nil
+	}
+	p.sleepDeadline = time.Time{}
+	p.setFadeLevel(1)
+}
+
+func (p *Player) GetSleepTimer() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sleepDone == nil {
+		return 0, false
+	}
+
+	remaining := p.sleepDeadline.Sub(p.clock.Now())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+func (p *Player) runSleepTimer(done chan struct{}) {
+	p.mu.Lock()
+	ticks, stop := p.clock.Tick(sleepTickInterval)
+	p.mu.Unlock()
+	defer stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticks:
+			if p.checkSleepTimer(done) {
+				return
+			}
+		}
+	}
+}
+
+// checkSleepTimer fades the volume over the last part of the timer and stops
+// playback once it runs out. It reports whether the timer done is finished.
+func (p *Player) checkSleepTimer(done chan struct{}) bool {
+	p.mu.Lock()
+	if p.sleepDone != done {
+		p.mu.Unlock()
+		return true
+	}
+
+	remaining := p.sleepDeadline.Sub(p.clock.Now())
+	if remaining > 0 {
+		if remaining < p.sleepFade {
+			p.setFadeLevel(float64(remaining) / float64(p.sleepFade))
+		}
+		p.mu.Unlock()
+		return false
+	}
+
+	p.sleepDone = nil
+	p.sleepDeadline = time.Time{}
+	p.mu.Unlock()
+
+	p.Stop()
+
+	p.mu.Lock()
+	p.setFadeLevel(1)
+	p.mu.Unlock()
+	return true
+}
+
+func (p *Player) setFadeLevel(level float64) {
+	p.fadeLevel = level
+	p.applyVolume()
+}
+
+func (p *Player) SetStopAfter(mode StopAfterMode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopAfter = mode
+	p.prepareNext()
+}
+
+func (p *Player) GetStopAfter() StopAfterMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopAfter
+}
+
+// CycleSleepTimer steps through off, the timed presets with a fade-out, stop
+// after the current track and stop after the current album.
+func (p *Player) CycleSleepTimer() {
+	remaining, active := p.GetSleepTimer()
+	stopAfter := p.GetStopAfter()
+
+	next := 0
+	switch {
+	case stopAfter == StopAfterAlbum:
+		p.SetStopAfter(StopAfterNone)
+		return
+	case stopAfter == StopAfterTrack:
+		p.SetStopAfter(StopAfterAlbum)
+		return
+	case active:
+		next = len(sleepTimerPresets)
+		for i, preset := range sleepTimerPresets {
+			if remaining <= preset {
+				next = i + 1
+				break
+			}
+		}
+	}
+
+	if next < len(sleepTimerPresets) {
+		p.SetSleepTimer(sleepTimerPresets[next], true)
+		return
+	}
+
+	p.CancelSleepTimer()
+	p.SetStopAfter(StopAfterTrack)
+}
+
+func (p *Player) stopsAfter(next Track) bool {
+	switch p.stopAfter {
+	case StopAfterTrack:
+		return true
+	case StopAfterAlbum:
+		current, _ := p.currentTrack()
+		return current.Album == "" ||
+			!strings.EqualFold(current.Album, next.Album) ||
+			!strings.EqualFold(current.Artist, next.Artist)
+	default:
+		return false
+	}
+}
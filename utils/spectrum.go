@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"math"
+	"math/cmplx"
+	"sync/atomic"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+const (
+	sampleRingSize  = 4096
+	spectrumFFTSize = 2048
+
+	spectrumMinFreq = 40.0
+	spectrumMaxFreq = 16000.0
+	spectrumFloorDB = -72.0
+
+	spectrumFallRate = 1.5
+	spectrumPeakHold = 600 * time.Millisecond
+	spectrumPeakFall = 0.8
+)
+
+// sampleRing is a single producer, single consumer ring of mono samples. The
+// audio thread writes without locking and readers take whatever was written
+// most recently.
+type sampleRing struct {
+	samples [sampleRingSize]atomic.Uint32
+	pos     atomic.Uint64
+}
+
+func (r *sampleRing) write(samples [][2]float64) {
+	pos := r.pos.Load()
+	for _, s := range samples {
+		r.samples[pos%sampleRingSize].Store(math.Float32bits(float32((s[0] + s[1]) / 2)))
+		pos++
+	}
+	r.pos.Store(pos)
+}
+
+func (r *sampleRing) read(dst []float64) {
+	pos := r.pos.Load()
+	start := pos - uint64(len(dst))
+	for i := range dst {
+		dst[i] = float64(math.Float32frombits(r.samples[(start+uint64(i))%sampleRingSize].Load()))
+	}
+}
+
+type sampleTap struct {
+	streamer beep.Streamer
+	ring     *sampleRing
+}
+
+func (t *sampleTap) Stream(samples [][2]float64) (int, bool) {
+	n, ok := t.streamer.Stream(samples)
+	t.ring.write(samples[:n])
+	return n, ok
+}
+
+func (t *sampleTap) Err() error {
+	return t.streamer.Err()
+}
+
+// ReadSamples fills dst with the most recent mono samples sent to the output.
+func (p *Player) ReadSamples(dst []float64) {
+	p.samples.read(dst)
+}
+
+func (p *Player) SampleRate() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int(p.outputRate)
+}
+
+// SpectrumAnalyzer turns the player's output into log-spaced frequency bars.
+// Bars rise immediately and fall back smoothly, and each keeps a peak marker
+// that holds briefly before dropping.
+type SpectrumAnalyzer struct {
+	samples    []float64
+	window     []float64
+	spectrum   []complex128
+	levels     []float64
+	peaks      []float64
+	peakTimes  []time.Time
+	lastUpdate time.Time
+}
+
+func NewSpectrumAnalyzer() *SpectrumAnalyzer {
+	window := make([]float64, spectrumFFTSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(spectrumFFTSize-1))
+	}
+
+	return &SpectrumAnalyzer{
+		samples:  make([]float64, spectrumFFTSize),
+		window:   window,
+		spectrum: make([]complex128, spectrumFFTSize),
+	}
+}
+
+// Update analyzes the latest output of p into bands bars.
+func (a *SpectrumAnalyzer) Update(p *Player, bands int, now time.Time) {
+	if bands <= 0 {
+		return
+	}
+	if len(a.levels) != bands {
+		a.levels = make([]float64, bands)
+		a.peaks = make([]float64, bands)
+		a.peakTimes = make([]time.Time, bands)
+	}
+
+	dt := now.Sub(a.lastUpdate).Seconds()
+	if a.lastUpdate.IsZero() || dt > 1 {
+		dt = 0
+	}
+	a.lastUpdate = now
+
+	targets := make([]float64, bands)
+	if p != nil && p.IsPlaying() {
+		p.ReadSamples(a.samples)
+		a.analyze(targets, float64(p.SampleRate()))
+	}
+
+	for i, target := range targets {
+		if target >= a.levels[i] {
+			a.levels[i] = target
+		} else {
+			a.levels[i] = math.Max(target, a.levels[i]-spectrumFallRate*dt)
+		}
+
+		if a.levels[i] >= a.peaks[i] {
+			a.peaks[i] = a.levels[i]
+			a.peakTimes[i] = now
+		} else if now.Sub(a.peakTimes[i]) > spectrumPeakHold {
+			a.peaks[i] = math.Max(a.levels[i], a.peaks[i]-spectrumPeakFall*dt)
+		}
+	}
+}
+
+func (a *SpectrumAnalyzer) analyze(bands []float64, sampleRate float64) {
+	for i, s := range a.samples {
+		a.spectrum[i] = complex(s*a.window[i], 0)
+	}
+	fft(a.spectrum)
+
+	binWidth := sampleRate / spectrumFFTSize
+	maxFreq := math.Min(spectrumMaxFreq, sampleRate/2)
+	ratio := maxFreq / spectrumMinFreq
+
+	for i := range bands {
+		lo := spectrumMinFreq * math.Pow(ratio, float64(i)/float64(len(bands)))
+		hi := spectrumMinFreq * math.Pow(ratio, float64(i+1)/float64(len(bands)))
+
+		first := int(lo / binWidth)
+		last := int(hi / binWidth)
+		if last <= first {
+			last = first + 1
+		}
+
+		var peak float64
+		for bin := first; bin < last && bin < spectrumFFTSize/2; bin++ {
+			peak = math.Max(peak, cmplx.Abs(a.spectrum[bin]))
+		}
+
+		// A full scale sine through the Hann window peaks at a quarter of the
+		// FFT size.
+		db := 20 * math.Log10(peak/(spectrumFFTSize/4)+1e-12)
+		bands[i] = math.Max(0, math.Min(1, 1-db/spectrumFloorDB))
+	}
+}
+
+func (a *SpectrumAnalyzer) Levels() []float64 {
+	return a.levels
+}
+
+func (a *SpectrumAnalyzer) Peaks() []float64 {
+	return a.peaks
+}
+
+// fft is an in-place iterative radix-2 FFT. len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, -2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
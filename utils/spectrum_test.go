@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSpectrumAnalyzer(t *testing.T) {
+	player, output, events := newTestPlayer(t, 1)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+	output.Advance(100 * time.Millisecond)
+
+	const bands = 24
+	analyzer := NewSpectrumAnalyzer()
+	analyzer.Update(player, bands, time.Now())
+
+	levels := append([]float64(nil), analyzer.Levels()...)
+	loudest := 0
+	for i, level := range levels {
+		if level > levels[loudest] {
+			loudest = i
+		}
+	}
+
+	ratio := spectrumMaxFreq / spectrumMinFreq
+	lo := spectrumMinFreq * math.Pow(ratio, float64(loudest)/bands)
+	hi := spectrumMinFreq * math.Pow(ratio, float64(loudest+1)/bands)
+	if lo > 440 || hi < 440 {
+		t.Fatalf("loudest band covers %.0f-%.0f Hz, want it to include the 440 Hz test tone", lo, hi)
+	}
+	if levels[loudest] < 0.8 {
+		t.Fatalf("440 Hz band level = %.2f, want close to full scale", levels[loudest])
+	}
+	if peaks := analyzer.Peaks(); peaks[loudest] != levels[loudest] {
+		t.Fatalf("peak = %.2f, want it to track the level %.2f", peaks[loudest], levels[loudest])
+	}
+
+	player.Pause()
+	analyzer.Update(player, bands, time.Now().Add(100*time.Millisecond))
+	if level := analyzer.Levels()[loudest]; level >= levels[loudest] || level <= 0 {
+		t.Fatalf("level after pausing = %.2f, want it to fall smoothly from %.2f", level, levels[loudest])
+	}
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+const (
+	MinPlaybackSpeed = 0.5
+	MaxPlaybackSpeed = 2.0
+
+	stretchFrame     = 30 * 44100 / 1000
+	stretchTolerance = 5 * 44100 / 1000
+)
+
+// timeStretcher changes tempo without changing pitch using WSOLA: frames are
+// read at speed times the output hop and each one is nudged within a small
+// window to the offset that best lines up with the previous frame before
+// being overlap-added with a Hann window.
+type timeStretcher struct {
+	streamer  beep.Streamer
+	speed     float64
+	frame     int
+	hop       int
+	tolerance int
+	window    []float64
+
+	in      [][2]float64
+	nominal float64
+	prevPos int
+	started bool
+	overlap [][2]float64
+	out     [][2]float64
+	pending [][2]float64
+	read    [][2]float64
+	drained bool
+	ended   bool
+}
+
+func newTimeStretcher(rate beep.SampleRate) *timeStretcher {
+	scale := float64(rate) / 44100
+	frame := int(stretchFrame*scale) &^ 1
+	t := &timeStretcher{
+		speed:     1,
+		frame:     frame,
+		hop:       frame / 2,
+		tolerance: int(stretchTolerance * scale),
+		window:    make([]float64, frame),
+		read:      make([][2]float64, 512),
+	}
+	for i := range t.window {
+		t.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frame))
+	}
+	t.reset()
+	return t
+}
+
+func (t *timeStretcher) reset() {
+	t.in = t.in[:0]
+	t.nominal = 0
+	t.prevPos = 0
+	t.started = false
+	t.overlap = make([][2]float64, t.hop)
+	t.out = t.out[:0]
+	t.pending = nil
+	t.drained = false
+	t.ended = false
+}
+
+func (t *timeStretcher) setSpeed(speed float64) {
+	if speed == t.speed {
+		return
+	}
+
+	if speed == 1 && t.started {
+		pending := append([][2]float64{}, t.out...)
+		if start := t.prevPos + t.hop; start < len(t.in) {
+			pending = append(pending, t.in[start:]...)
+		}
+		t.reset()
+		t.pending = pending
+	}
+	t.speed = speed
+}
+
+func (t *timeStretcher) Stream(samples [][2]float64) (n int, ok bool) {
+	if len(t.pending) > 0 {
+		n = copy(samples, t.pending)
+		t.pending = t.pending[n:]
+		if n == len(samples) {
+			return n, true
+		}
+	}
+
+	if t.speed == 1 {
+		sn, sok := t.streamer.Stream(samples[n:])
+		n += sn
+		return n, n > 0 || sok
+	}
+
+	for n < len(samples) {
+		if len(t.out) == 0 && !t.process() {
+			break
+		}
+		c := copy(samples[n:], t.out)
+		t.out = t.out[c:]
+		n += c
+	}
+	return n, n > 0
+}
+
+func (t *timeStretcher) fill(size int) {
+	for !t.drained && len(t.in) < size {
+		want := size - len(t.in)
+		if want > len(t.read) {
+			want = len(t.read)
+		}
+		sn, sok := t.streamer.Stream(t.read[:want])
+		t.in = append(t.in, t.read[:sn]...)
+		if !sok || sn == 0 {
+			t.drained = true
+		}
+	}
+}
+
+func (t *timeStretcher) sample(i int) [2]float64 {
+	if i < len(t.in) {
+		return t.in[i]
+	}
+	return [2]float64{}
+}
+
+// process appends one hop of output and reports whether it produced any.
+func (t *timeStretcher) process() bool {
+	if t.ended {
+		return false
+	}
+
+	nominal := int(t.nominal)
+	t.fill(nominal + t.tolerance + t.frame)
+
+	if t.drained && nominal >= len(t.in) {
+		t.out = append(t.out, t.overlap...)
+		t.ended = true
+		return len(t.out) > 0
+	}
+
+	pos := nominal
+	if t.started {
+		pos = t.bestOffset(nominal)
+	}
+
+	for i := 0; i < t.hop; i++ {
+		s := t.sample(pos + i)
+		if !t.started {
+			t.out = append(t.out, s)
+			continue
+		}
+		w := t.window[i]
+		t.out = append(t.out, [2]float64{t.overlap[i][0] + s[0]*w, t.overlap[i][1] + s[1]*w})
+	}
+	for i := 0; i < t.hop; i++ {
+		s := t.sample(pos + t.hop + i)
+		w := t.window[t.hop+i]
+		t.overlap[i] = [2]float64{s[0] * w, s[1] * w}
+	}
+
+	t.prevPos = pos
+	t.started = true
+	t.nominal += float64(t.hop) * t.speed
+	t.trim()
+	return true
+}
+
+func (t *timeStretcher) bestOffset(nominal int) int {
+	template := t.prevPos + t.hop
+	start := nominal - t.tolerance
+	if start < 0 {
+		start = 0
+	}
+	end := nominal + t.tolerance
+
+	best, bestScore := nominal, math.Inf(-1)
+	for pos := start; pos <= end; pos++ {
+		var corr, energy float64
+		for i := 0; i < t.hop; i += 2 {
+			a, b := t.sample(pos+i), t.sample(template+i)
+			corr += (a[0] + a[1]) * (b[0] + b[1])
+			energy += (a[0] + a[1]) * (a[0] + a[1])
+		}
+		score := corr
+		if energy > 0 {
+			score = corr / math.Sqrt(energy)
+		}
+		if score > bestScore {
+			best, bestScore = pos, score
+		}
+	}
+	return best
+}
+
+func (t *timeStretcher) trim() {
+	keep := int(t.nominal) - t.tolerance
+	if template := t.prevPos + t.hop; template < keep {
+		keep = template
+	}
+	if keep <= 0 {
+		return
+	}
+	if keep > len(t.in) {
+		keep = len(t.in)
+	}
+
+	t.in = append(t.in[:0], t.in[keep:]...)
+	t.nominal -= float64(keep)
+	t.prevPos -= keep
+}
+
+func (t *timeStretcher) Err() error {
+	return t.streamer.Err()
+}
+
+// speedControl fields are guarded by the output lock once it is playing.
+type speedControl struct {
+	streamer      beep.Streamer
+	speed         float64
+	preservePitch bool
+	stretcher     *timeStretcher
+	resampler     *beep.Resampler
+}
+
+func newSpeedControl(rate beep.SampleRate) *speedControl {
+	return &speedControl{
+		speed:         1,
+		preservePitch: true,
+		stretcher:     newTimeStretcher(rate),
+	}
+}
+
+func (s *speedControl) setStreamer(streamer beep.Streamer) {
+	s.streamer = streamer
+	s.reset()
+}
+
+func (s *speedControl) setSampleRate(rate beep.SampleRate) {
+	s.stretcher = newTimeStretcher(rate)
+	s.reset()
+}
+
+func (s *speedControl) reset() {
+	s.stretcher.streamer = s.streamer
+	s.stretcher.reset()
+	s.stretcher.speed = s.stretchSpeed()
+	s.resampler = nil
+	if s.streamer != nil && !s.preservePitch {
+		s.resampler = beep.ResampleRatio(resampleQuality, s.speed, s.streamer)
+	}
+}
+
+func (s *speedControl) stretchSpeed() float64 {
+	if s.preservePitch {
+		return s.speed
+	}
+	return 1
+}
+
+func (s *speedControl) setSpeed(speed float64) {
+	s.speed = speed
+	s.stretcher.setSpeed(s.stretchSpeed())
+	if s.resampler != nil {
+		s.resampler.SetRatio(speed)
+	}
+}
+
+func (s *speedControl) setPreservePitch(preserve bool) {
+	s.preservePitch = preserve
+	s.reset()
+}
+
+// buffered is how many samples of its input the time stretcher has read but
+// not played yet.
+func (s *speedControl) buffered() int {
+	if !s.preservePitch {
+		return 0
+	}
+	t := s.stretcher
+	n := len(t.pending) + len(t.in) - int(t.nominal) + int(float64(len(t.out))*t.speed)
+	return max(n, 0)
+}
+
+func (s *speedControl) Stream(samples [][2]float64) (n int, ok bool) {
+	if s.streamer == nil {
+		return 0, false
+	}
+
+	if s.preservePitch {
+		return s.stretcher.Stream(samples)
+	}
+	return s.resampler.Stream(samples)
+}
+
+func (s *speedControl) Err() error {
+	if s.streamer == nil {
+		return nil
+	}
+	return s.streamer.Err()
+}
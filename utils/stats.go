@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TrackStats struct {
+	PlayCount  int
+	SkipCount  int
+	LastPlayed time.Time
+	FirstAdded time.Time `json:",omitzero"`
+}
+
+// statsSaveDelay batches the writes from a run of track ends into one.
+const statsSaveDelay = 5 * time.Second
+
+// added.json holds when every track was first seen, as Unix seconds.
+type StatsStore struct {
+	path       string
+	addedPath  string
+	mu         sync.Mutex
+	stats      map[string]*TrackStats
+	added      map[string]int64
+	statsDirty bool
+	addedDirty bool
+	saveTimer  *time.Timer
+	version    int
+}
+
+func NewStatsStore() (*StatsStore, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, err
+	}
+
+	ss := newStatsStore(filepath.Join(configDir, "stats.json"), filepath.Join(configDir, "added.json"))
+	if err := ss.load(); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func newStatsStore(path, addedPath string) *StatsStore {
+	return &StatsStore{
+		path:      path,
+		addedPath: addedPath,
+		stats:     make(map[string]*TrackStats),
+		added:     make(map[string]int64),
+	}
+}
+
+func StatsKey(track Track) string {
+	if track.Title != "" {
+		return strings.ToLower(strings.Join([]string{"tag", track.Artist, track.Album, track.Title}, "|"))
+	}
+	return strings.ToLower("file|" + filepath.Base(filepath.Dir(track.Path)) + "/" + filepath.Base(track.Path))
+}
+
+func (ss *StatsStore) load() error {
+	if err := readJSON(ss.addedPath, &ss.added); err != nil {
+		return err
+	}
+	if err := readJSON(ss.path, &ss.stats); err != nil {
+		return err
+	}
+
+	// Older files kept an entry with the added date for every track.
+	for key, stats := range ss.stats {
+		if !stats.FirstAdded.IsZero() {
+			if _, ok := ss.added[key]; !ok {
+				ss.added[key] = stats.FirstAdded.Unix()
+				ss.addedDirty = true
+			}
+			stats.FirstAdded = time.Time{}
+			ss.statsDirty = true
+		}
+		if stats.PlayCount == 0 && stats.SkipCount == 0 {
+			delete(ss.stats, key)
+			ss.statsDirty = true
+		}
+	}
+	if ss.statsDirty || ss.addedDirty {
+		ss.scheduleSave()
+	}
+	return nil
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Callers hold ss.mu.
+func (ss *StatsStore) scheduleSave() {
+	ss.version++
+	if ss.saveTimer == nil {
+		ss.saveTimer = time.AfterFunc(statsSaveDelay, func() { ss.Flush() })
+	}
+}
+
+func (ss *StatsStore) Flush() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.saveTimer != nil {
+		ss.saveTimer.Stop()
+		ss.saveTimer = nil
+	}
+
+	var errs []error
+	if ss.statsDirty {
+		if err := writeJSON(ss.path, ss.stats); err != nil {
+			errs = append(errs, err)
+		} else {
+			ss.statsDirty = false
+		}
+	}
+	if ss.addedDirty {
+		if err := writeJSON(ss.addedPath, ss.added); err != nil {
+			errs = append(errs, err)
+		} else {
+			ss.addedDirty = false
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (ss *StatsStore) Version() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.version
+}
+
+func (ss *StatsStore) Get(track Track) TrackStats {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	key := StatsKey(track)
+	var stats TrackStats
+	if s, ok := ss.stats[key]; ok {
+		stats = *s
+	}
+	if added, ok := ss.added[key]; ok {
+		stats.FirstAdded = time.Unix(added, 0)
+	}
+	return stats
+}
+
+// Skips count against a track like plays do.
+func (ss *StatsStore) ShuffleWeight(track Track) float64 {
+	stats := ss.Get(track)
+	return 1 / float64(1+stats.PlayCount+stats.SkipCount)
+}
+
+func (ss *StatsStore) AddTracks(tracks []Track, now time.Time) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	changed := false
+	for _, track := range tracks {
+		key := StatsKey(track)
+		if _, ok := ss.added[key]; !ok {
+			ss.added[key] = now.Unix()
+			changed = true
+		}
+	}
+
+	if changed {
+		ss.addedDirty = true
+		ss.scheduleSave()
+	}
+}
+
+// Stopping playback is neither a play nor a skip.
+func (ss *StatsStore) Record(event PlayerEvent) {
+	if event.Type != EventTrackEnded || (event.Reason != EndCompleted && event.Reason != EndSkipped) {
+		return
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	key := StatsKey(event.Track)
+	stats, ok := ss.stats[key]
+	if !ok {
+		stats = &TrackStats{}
+		ss.stats[key] = stats
+	}
+	if _, ok := ss.added[key]; !ok {
+		ss.added[key] = event.Time.Unix()
+		ss.addedDirty = true
+	}
+
+	if event.Reason == EndCompleted {
+		stats.PlayCount++
+		stats.LastPlayed = event.Time
+	} else {
+		stats.SkipCount++
+	}
+
+	ss.statsDirty = true
+	ss.scheduleSave()
+}
+
+func (ss *StatsStore) Watch(p *Player) func() {
+	events, unsubscribe := p.Subscribe()
+	go func() {
+		for event := range events {
+			ss.Record(event)
+		}
+	}()
+	return unsubscribe
+}
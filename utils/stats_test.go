@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsRecordsPlaysAndSkips(t *testing.T) {
+	dir := t.TempDir()
+	stats := newStatsStore(filepath.Join(dir, "stats.json"), filepath.Join(dir, "added.json"))
+
+	added := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	track := Track{Path: "/music/a/01.flac", Title: "Song", Artist: "Band", Album: "Record"}
+	unplayed := Track{Path: "/music/a/02.flac", Title: "Other", Artist: "Band", Album: "Record"}
+	stats.AddTracks([]Track{track, unplayed}, added)
+
+	played := added.Add(time.Hour)
+	events := []PlayerEvent{
+		{Type: EventTrackStarted, Track: track, Time: played},
+		{Type: EventTrackEnded, Track: track, Time: played, Completed: true, Reason: EndCompleted},
+		{Type: EventTrackEnded, Track: track, Time: played, Position: 10 * time.Second, Reason: EndSkipped},
+		{Type: EventTrackEnded, Track: track, Time: played, Position: 20 * time.Second, Reason: EndStopped},
+	}
+	for _, event := range events {
+		stats.Record(event)
+	}
+
+	if _, err := os.Stat(stats.path); !os.IsNotExist(err) {
+		t.Fatalf("stats were saved before the save delay: %v", err)
+	}
+	if err := stats.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := newStatsStore(stats.path, stats.addedPath)
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.stats) != 1 {
+		t.Fatalf("stats.json has %d entries, want only the played track", len(reloaded.stats))
+	}
+
+	moved := track
+	moved.Path = "/new/library/a/01.flac"
+	got := reloaded.Get(moved)
+	if got.PlayCount != 1 || got.SkipCount != 1 {
+		t.Fatalf("got %d plays and %d skips, want 1 and 1", got.PlayCount, got.SkipCount)
+	}
+	if !got.FirstAdded.Equal(added) || !got.LastPlayed.Equal(played) {
+		t.Fatalf("got added %v and last played %v, want %v and %v", got.FirstAdded, got.LastPlayed, added, played)
+	}
+	if got := reloaded.Get(unplayed); !got.FirstAdded.Equal(added) || got.PlayCount != 0 {
+		t.Fatalf("unplayed track stats = %+v, want only the added date", got)
+	}
+}
+
+func TestStatsMigratesAddedDates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.json")
+	old := `{
+		"tag|band|record|song": {"PlayCount": 2, "SkipCount": 0, "LastPlayed": "2026-10-02T12:00:00Z", "FirstAdded": "2026-10-01T12:00:00Z"},
+		"tag|band|record|other": {"PlayCount": 0, "SkipCount": 0, "LastPlayed": "0001-01-01T00:00:00Z", "FirstAdded": "2026-10-01T12:00:00Z"}
+	}`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := newStatsStore(path, filepath.Join(dir, "added.json"))
+	if err := stats.load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stats.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "FirstAdded") || strings.Contains(string(data), "other") {
+		t.Fatalf("stats.json still has added dates or unplayed tracks: %s", data)
+	}
+
+	reloaded := newStatsStore(path, stats.addedPath)
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	added := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, title := range []string{"Song", "Other"} {
+		got := reloaded.Get(Track{Title: title, Artist: "Band", Album: "Record"})
+		if !got.FirstAdded.Equal(added) {
+			t.Errorf("%s added %v, want %v", title, got.FirstAdded, added)
+		}
+	}
+	if got := reloaded.Get(Track{Title: "Song", Artist: "Band", Album: "Record"}); got.PlayCount != 2 {
+		t.Errorf("play count = %d after migrating, want 2", got.PlayCount)
+	}
+}
+
+func TestStopIsNotASkip(t *testing.T) {
+	player, _, events := newTestPlayer(t, 2)
+
+	if err := player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, EventTrackStarted)
+
+	if err := player.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if ended := waitForEvent(t, events, EventTrackEnded); ended.Reason != EndSkipped {
+		t.Fatalf("Next ended the track as %s, want skipped", ended.Reason)
+	}
+
+	player.Close()
+	if ended := waitForEvent(t, events, EventTrackEnded); ended.Reason != EndStopped || ended.Completed {
+		t.Fatalf("Close ended the track as %s, want stopped", ended.Reason)
+	}
+}
+
+func TestStatsKeyWithoutTags(t *testing.T) {
+	a := StatsKey(Track{Path: "/music/Album/01.mp3"})
+	b := StatsKey(Track{Path: "/mnt/backup/music/Album/01.mp3"})
+	c := StatsKey(Track{Path: "/music/Other/01.mp3"})
+
+	if a != b {
+		t.Errorf("keys differ for the same file under another root: %q, %q", a, b)
+	}
+	if a == c {
+		t.Errorf("keys match for files in different albums: %q", a)
+	}
+}
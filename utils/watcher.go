@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	watchDebounce = 750 * time.Millisecond
+	watchMaxDelay = 10 * time.Second
+
+	defaultLibraryPollInterval = time.Minute
+)
+
+// LibraryWatcher reports changes to the files under a library root. Bursts of
+// changes, like a large copy, are reported once things have been quiet for
+// watchDebounce, but never later than watchMaxDelay after the first one.
+type LibraryWatcher struct {
+	root         string
+	pollInterval time.Duration
+	changes      chan struct{}
+	errors       chan error
+	touches      chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
+	platformWatcher
+}
+
+// WatchLibrary starts watching root and every directory below it, including
+// ones created later. Where changes can't be watched for, the tree is walked
+// every pollInterval instead.
+func WatchLibrary(root string, pollInterval time.Duration) (*LibraryWatcher, error) {
+	w := &LibraryWatcher{
+		root:         root,
+		pollInterval: pollInterval,
+		changes:      make(chan struct{}, 1),
+		errors:       make(chan error, 1),
+		touches:      make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	if err := w.start(); err != nil {
+		return nil, err
+	}
+
+	go w.debounce()
+	return w, nil
+}
+
+func (w *LibraryWatcher) Root() string {
+	return w.root
+}
+
+// Changes receives a value after each settled burst of changes and is closed
+// when the watcher is.
+func (w *LibraryWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Errors receives problems that leave part of the tree unwatched, like running
+// out of inotify watches, which switches the watcher to polling. Only the
+// first of several waiting to be received is kept.
+func (w *LibraryWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *LibraryWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.stop()
+	})
+	return err
+}
+
+// touch is called by the platform watcher for every relevant change.
+func (w *LibraryWatcher) touch() {
+	select {
+	case w.touches <- struct{}{}:
+	default:
+	}
+}
+
+// fail is called by the platform watcher when part of the tree can't be
+// watched.
+func (w *LibraryWatcher) fail(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func (w *LibraryWatcher) debounce() {
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	var burstStart time.Time
+	for {
+		select {
+		case <-w.touches:
+			if burstStart.IsZero() {
+				burstStart = time.Now()
+			}
+			timer.Reset(min(watchDebounce, watchMaxDelay-time.Since(burstStart)))
+		case <-timer.C:
+			burstStart = time.Time{}
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		case <-w.done:
+			close(w.changes)
+			return
+		}
+	}
+}
+
+// isLibraryFile reports whether a change to path can affect the library.
+func isLibraryFile(path string) bool {
+	return isAudioFile(path) || isCueSheet(path)
+}
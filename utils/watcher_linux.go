@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// platformWatcher uses inotify, which watches single directories, so every
+// directory in the tree gets its own watch.
+type platformWatcher struct {
+	fd      int
+	file    *os.File
+	watches map[int32]string
+	polling bool
+}
+
+// unwatchedError lists the directories left without a watch because the
+// inotify limit was reached.
+type unwatchedError struct {
+	dirs []string
+}
+
+func (e *unwatchedError) Error() string {
+	if len(e.dirs) == 1 {
+		return fmt.Sprintf("no inotify watches left for %s, raise fs.inotify.max_user_watches", e.dirs[0])
+	}
+	return fmt.Sprintf("no inotify watches left for %s and %d more directories, raise fs.inotify.max_user_watches", e.dirs[0], len(e.dirs)-1)
+}
+
+func (e *unwatchedError) Unwrap() error {
+	return syscall.ENOSPC
+}
+
+func (w *LibraryWatcher) start() error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("inotify: %v", err)
+	}
+
+	// A non-blocking descriptor goes through the runtime poller, so closing
+	// the file wakes up the reader. Calling Fd would make it blocking again,
+	// so the descriptor is kept for adding and removing watches.
+	w.fd = fd
+	w.file = os.NewFile(uintptr(fd), "inotify")
+	w.watches = make(map[int32]string)
+	if err := w.addTree(w.root); err != nil {
+		if !errors.Is(err, syscall.ENOSPC) {
+			w.file.Close()
+			return err
+		}
+		if err := w.fallBackToPolling(err); err != nil {
+			w.file.Close()
+			return err
+		}
+	}
+
+	go w.readEvents()
+	return nil
+}
+
+func (w *LibraryWatcher) stop() error {
+	return w.file.Close()
+}
+
+// fallBackToPolling polls the whole tree once some of it can't be watched,
+// keeping the watches there are for quicker updates, and reports err.
+func (w *LibraryWatcher) fallBackToPolling(err error) error {
+	if !w.polling {
+		if err := w.poll(); err != nil {
+			return err
+		}
+		w.polling = true
+	}
+	w.fail(err)
+	return nil
+}
+
+// addTree watches dir and the directories below it. Directories left out for
+// lack of watches are returned in an unwatchedError, anything else just leaves
+// that directory out.
+func (w *LibraryWatcher) addTree(dir string) error {
+	var unwatched []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err == syscall.ENOSPC {
+			unwatched = append(unwatched, path)
+			return nil
+		}
+		if err != nil {
+			if path == dir {
+				return fmt.Errorf("watching %s: %w", path, err)
+			}
+			return nil
+		}
+		w.watches[int32(wd)] = path
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(unwatched) > 0 {
+		return &unwatchedError{dirs: unwatched}
+	}
+	return nil
+}
+
+// removeTree drops the watches on dir and the directories below it. A
+// directory moved elsewhere keeps its watches, which would go on reporting
+// its changes under the path it was moved from. Moving it within the tree
+// watches it again under its new path.
+func (w *LibraryWatcher) removeTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for wd, path := range w.watches {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, wd)
+		}
+	}
+}
+
+func (w *LibraryWatcher) readEvents() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			length := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+
+			name := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+length]
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			offset += syscall.SizeofInotifyEvent + length
+
+			w.handleEvent(wd, mask, string(name))
+		}
+	}
+}
+
+func (w *LibraryWatcher) handleEvent(wd int32, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		w.touch()
+		return
+	}
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.watches, wd)
+		return
+	}
+
+	dir, ok := w.watches[wd]
+	if !ok {
+		return
+	}
+	path := filepath.Join(dir, name)
+
+	switch {
+	case mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+		w.touch()
+	case mask&syscall.IN_ISDIR != 0:
+		// A new directory may already hold files by the time it is watched,
+		// but those are picked up by the rescan this triggers. One that is
+		// gone again by then needs no watch.
+		if mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+			err := w.addTree(path)
+			switch {
+			case errors.Is(err, syscall.ENOSPC):
+				if err := w.fallBackToPolling(err); err != nil {
+					w.fail(err)
+				}
+			case err != nil && !errors.Is(err, fs.ErrNotExist):
+				w.fail(err)
+			}
+		}
+		if mask&syscall.IN_MOVED_FROM != 0 {
+			w.removeTree(path)
+		}
+		w.touch()
+	case isLibraryFile(path):
+		w.touch()
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLibraryWatcher(t *testing.T) {
+	dir := t.TempDir()
+	watcher, err := WatchLibrary(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	expectChange := func(what string) {
+		t.Helper()
+		select {
+		case <-watcher.Changes():
+		case <-time.After(watchDebounce + 2*time.Second):
+			t.Fatalf("no change reported after %s", what)
+		}
+	}
+
+	sub := filepath.Join(dir, "new album")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("creating a directory")
+
+	for _, name := range []string{"1.wav", "2.wav", "3.wav"} {
+		writeTestTrack(t, sub, name, 50*time.Millisecond)
+	}
+	expectChange("copying into the new directory")
+
+	select {
+	case <-watcher.Changes():
+		t.Fatal("a burst of changes was reported more than once")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	if err := os.WriteFile(filepath.Join(sub, "notes.txt"), []byte("liner notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-watcher.Changes():
+		t.Fatal("a change to a non-audio file was reported")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	if err := os.Rename(filepath.Join(sub, "1.wav"), filepath.Join(dir, "1.wav")); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("moving a track")
+
+	moved := filepath.Join(t.TempDir(), "moved away")
+	if err := os.Rename(sub, moved); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("moving a directory out of the library")
+
+	writeTestTrack(t, moved, "4.wav", 50*time.Millisecond)
+	select {
+	case <-watcher.Changes():
+		t.Fatal("a change outside the library was reported")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	watcher.Close()
+	if _, ok := <-watcher.Changes(); ok {
+		t.Fatal("changes still open after Close")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package utils
+
+// platformWatcher polls the tree where inotify isn't available.
+type platformWatcher struct{}
+
+func (w *LibraryWatcher) start() error {
+	return w.poll()
+}
+
+func (w *LibraryWatcher) stop() error {
+	return nil
+}
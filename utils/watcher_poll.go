@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// poll walks the tree every pollInterval until the watcher is closed,
+// comparing a fingerprint of every library file's size and modification time.
+func (w *LibraryWatcher) poll() error {
+	fingerprint, err := w.fingerprint()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if current, err := w.fingerprint(); err == nil && current != fingerprint {
+					fingerprint = current
+					w.touch()
+				}
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (w *LibraryWatcher) fingerprint() (uint64, error) {
+	hash := fnv.New64a()
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == w.root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() || !isLibraryFile(path) {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(hash, "%s|%d|%d\n", path, info.ModTime().UnixNano(), info.Size())
+		}
+		return nil
+	})
+	return hash.Sum64(), err
+}
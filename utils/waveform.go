@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	waveformResolution = 1024
+	waveformWindow     = 1024
+)
+
+// Waveform holds the peak and RMS amplitude of a track split into equal
+// slices of time.
+type Waveform struct {
+	Peaks []float64
+	RMS   []float64
+}
+
+func getWaveformCachePath(path string, info os.FileInfo) (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum(fmt.Appendf(nil, "%s|%d|%d", path, info.ModTime().UnixNano(), info.Size()))
+	return filepath.Join(configDir, "waveforms", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// LoadWaveform returns the waveform of track, decoding it when there is no
+// cached copy for the audio file's current modification time and size.
+func LoadWaveform(track Track) (*Waveform, error) {
+	info, err := os.Stat(track.AudioFile())
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath, err := getWaveformCachePath(track.Path, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var waveform Waveform
+		if err := json.Unmarshal(data, &waveform); err == nil {
+			return &waveform, nil
+		}
+	}
+
+	waveform, err := computeWaveform(track, waveformResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+		if data, err := json.Marshal(waveform); err == nil {
+			writeFileAtomic(cachePath, data)
+		}
+	}
+
+	return waveform, nil
+}
+
+func computeWaveform(track Track, buckets int) (*Waveform, error) {
+	f, err := os.Open(track.AudioFile())
+	if err != nil {
+		return nil, err
+	}
+
+	streamer, format, err := decodeAudioFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer streamer.Close()
+
+	if track.isRegion() {
+		region, err := newRegionStreamer(streamer, format, track)
+		if err != nil {
+			return nil, err
+		}
+		streamer = region
+	}
+
+	total := streamer.Len()
+	if total <= 0 {
+		return nil, fmt.Errorf("no audio in %s", filepath.Base(track.AudioFile()))
+	}
+	buckets = min(buckets, total)
+
+	waveform := &Waveform{
+		Peaks: make([]float64, buckets),
+		RMS:   make([]float64, buckets),
+	}
+	counts := make([]int, buckets)
+
+	buf := make([][2]float64, waveformWindow)
+	position := 0
+	for {
+		n, ok := streamer.Stream(buf)
+		for _, s := range buf[:n] {
+			i := min(position*buckets/total, buckets-1)
+			v := (s[0] + s[1]) / 2
+			waveform.Peaks[i] = math.Max(waveform.Peaks[i], math.Abs(v))
+			waveform.RMS[i] += v * v
+			counts[i]++
+			position++
+		}
+		if !ok {
+			break
+		}
+	}
+	if err := streamer.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, count := range counts {
+		if count > 0 {
+			waveform.RMS[i] = math.Sqrt(waveform.RMS[i] / float64(count))
+		}
+	}
+
+	return waveform, nil
+}
+
+// Columns resamples the waveform to width columns, keeping the loudest peak
+// and the average power of each column.
+func (w *Waveform) Columns(width int) ([]float64, []float64) {
+	peaks := make([]float64, width)
+	rms := make([]float64, width)
+	if len(w.Peaks) == 0 || len(w.RMS) != len(w.Peaks) {
+		return peaks, rms
+	}
+
+	for i := 0; i < width; i++ {
+		first := i * len(w.Peaks) / width
+		last := (i + 1) * len(w.Peaks) / width
+		if last <= first {
+			last = first + 1
+		}
+
+		var sum float64
+		for j := first; j < last; j++ {
+			peaks[i] = math.Max(peaks[i], w.Peaks[j])
+			sum += w.RMS[j] * w.RMS[j]
+		}
+		rms[i] = math.Sqrt(sum / float64(last-first))
+	}
+
+	return peaks, rms
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/generators"
+	"github.com/faiface/beep/wav"
+)
+
+func TestWaveformShowsSilence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "gap.wav")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := beep.Format{SampleRate: defaultOutputSampleRate, NumChannels: 2, Precision: 2}
+	tone, err := generators.SinTone(format.SampleRate, 440)
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := format.SampleRate.N(testTrackLength / 2)
+	if err := wav.Encode(file, beep.Seq(beep.Take(half, tone), beep.Silence(half)), format); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	waveform, err := LoadWaveform(Track{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peaks, rms := waveform.Columns(10)
+	for i := 0; i < 4; i++ {
+		if peaks[i] < 0.4 || rms[i] < 0.3 {
+			t.Errorf("column %d: peak %.2f, rms %.2f, want the tone", i, peaks[i], rms[i])
+		}
+	}
+	for i := 6; i < 10; i++ {
+		if peaks[i] != 0 || rms[i] != 0 {
+			t.Errorf("column %d: peak %.2f, rms %.2f, want silence", i, peaks[i], rms[i])
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachePath, err := getWaveformCachePath(path, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("waveform was not cached: %v", err)
+	}
+}